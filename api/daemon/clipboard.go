@@ -5,9 +5,11 @@
 package daemon
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -18,6 +20,7 @@ import (
 	"changkun.de/x/midgard/internal/hotkey"
 	"changkun.de/x/midgard/internal/types"
 	"changkun.de/x/midgard/internal/utils"
+	"google.golang.org/grpc/status"
 )
 
 func (m *Daemon) watchLocalClipboard(ctx context.Context) {
@@ -37,16 +40,18 @@ func (m *Daemon) watchLocalClipboard(ctx context.Context) {
 		}()
 
 		log.Println("hotkey triggered")
-		res, err := utils.Request(
+		code, res, err := utils.RequestStatus(
 			http.MethodPut,
 			types.EndpointAllocateURL,
-			&types.AllocateURLInput{
-				Source: types.SourceUniversalClipboard,
-			})
+			m.clipboardAllocation())
 		if err != nil {
 			msg = fmt.Sprintf("cannot perform allocate request, err: %v", err)
 			return
 		}
+		if err := responseError(code, res); err != nil {
+			msg = status.Convert(err).Message()
+			return
+		}
 		var out types.AllocateURLOutput
 		err = json.Unmarshal(res, &out)
 		if err != nil {
@@ -76,34 +81,109 @@ func (m *Daemon) watchLocalClipboard(ctx context.Context) {
 				continue
 			}
 
-			d := &types.PutToUniversalClipboardInput{}
-			d.Type = types.MIMEPlainText
-			d.Data = utils.BytesToString(text)
-			d.DaemonID = m.ID
-			b, _ := json.Marshal(d)
 			log.Println("local clipboard has changed as text, sync to server...")
-			m.writeCh <- &types.WebsocketMessage{
-				Action:  types.ActionClipboardPut,
-				UserID:  m.ID,
-				Message: "local clipboard has changed",
-				Data:    b,
-			}
+			m.putClipboard(types.Representation{Type: types.MIMEPlainText, Data: text})
 		case img, ok := <-imagCh:
 			if !ok {
 				return
 			}
-			d := &types.PutToUniversalClipboardInput{}
-			d.Type = types.MIMEImagePNG
-			d.Data = base64.StdEncoding.EncodeToString(img)
-			d.DaemonID = m.ID
-			b, _ := json.Marshal(d)
 			log.Println("local clipboard has changed as image, sync to server...")
-			m.writeCh <- &types.WebsocketMessage{
-				Action:  types.ActionClipboardPut,
-				UserID:  m.ID,
-				Message: "local clipboard has changed",
-				Data:    b,
-			}
+			m.putClipboard(types.Representation{Type: types.MIMEImagePNG, Data: img})
 		}
 	}
 }
+
+// putClipboard sends the changed local clipboard to the server. Other
+// representations that are available in the local clipboard, e.g. the
+// path of a copied image, are sent along as alternatives.
+func (m *Daemon) putClipboard(changed types.Representation) {
+	if m.appliedFromServer(changed) {
+		log.Println("local clipboard is applied from server, ignored.")
+		return
+	}
+
+	reps := []types.Representation{changed}
+	for _, r := range clipboard.Local.ReadAll() {
+		if r.Type != changed.Type {
+			reps = append(reps, r)
+		}
+	}
+
+	d := &types.PutToUniversalClipboardInput{
+		ClipboardData: types.EncodeClipboardData(reps...),
+		DaemonID:      m.ID,
+		Channel:       config.D().Publish,
+	}
+	if m.cipher != nil {
+		var err error
+		d.ClipboardData, err = m.cipher.Seal(d.ClipboardData)
+		if err != nil {
+			log.Printf("failed to encrypt clipboard data: %v", err)
+			return
+		}
+	}
+	b, _ := json.Marshal(d)
+	m.writeCh <- &types.WebsocketMessage{
+		Action:  types.ActionClipboardPut,
+		UserID:  m.ID,
+		Message: "local clipboard has changed",
+		Data:    b,
+	}
+}
+
+// apply remembers the given clipboard data that is applied from the
+// server to the local clipboard.
+func (m *Daemon) apply(reps []types.Representation) {
+	m.appliedMu.Lock()
+	defer m.appliedMu.Unlock()
+	m.applied = reps
+}
+
+// appliedFromServer reports whether the changed local clipboard is the
+// data that was last applied from the server, which must not be sent
+// back. The server cannot recognize such data as a duplicate if it is
+// encrypted, since each encryption is different. Each applied
+// representation is recognized once, since all of them are written to
+// the local clipboard. The data is forgotten on any other change of the
+// local clipboard, so copying it again is synced.
+func (m *Daemon) appliedFromServer(changed types.Representation) bool {
+	m.appliedMu.Lock()
+	defer m.appliedMu.Unlock()
+
+	for i, r := range m.applied {
+		if r.Type == changed.Type && bytes.Equal(r.Data, changed.Data) {
+			m.applied = append(m.applied[:i:i], m.applied[i+1:]...)
+			return true
+		}
+	}
+	m.applied = nil
+	return false
+}
+
+// clipboardAllocation returns the input to allocate a URL for the
+// universal clipboard. If end-to-end encryption is enabled, the server
+// cannot read the universal clipboard, hence the plain data from the
+// local clipboard is uploaded explicitly as an attachment.
+func (m *Daemon) clipboardAllocation() *types.AllocateURLInput {
+	if m.cipher == nil {
+		return &types.AllocateURLInput{Source: types.SourceUniversalClipboard}
+	}
+
+	t, buf := clipboard.Local.Read()
+	return &types.AllocateURLInput{
+		Source: types.SourceAttachment,
+		Type:   t,
+		Data:   base64.StdEncoding.EncodeToString(buf),
+	}
+}
+
+// decrypt decrypts the given clipboard data if it is encrypted.
+func (m *Daemon) decrypt(d types.ClipboardData) (types.ClipboardData, error) {
+	if d.Type != types.MIMEEncrypted {
+		return d, nil
+	}
+	if m.cipher == nil {
+		return d, errors.New("received encrypted clipboard data but no key is configured")
+	}
+	return m.cipher.Open(d)
+}
@@ -16,6 +16,18 @@ import (
 
 // Connect connects to a midgard client
 func Connect(callback func(ctx context.Context, c proto.MidgardClient)) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	connect(ctx, callback)
+}
+
+// ConnectStream is like Connect but without a timeout, it is used by
+// long running requests such as uploads of large files.
+func ConnectStream(callback func(ctx context.Context, c proto.MidgardClient)) {
+	connect(context.Background(), callback)
+}
+
+func connect(ctx context.Context, callback func(ctx context.Context, c proto.MidgardClient)) {
 	// We don't need authentication here. Daemon is running
 	// on a local machine.
 	conn, err := grpc.Dial(config.D().Addr, grpc.WithInsecure())
@@ -23,8 +35,5 @@ func Connect(callback func(ctx context.Context, c proto.MidgardClient)) {
 		log.Fatalf("did not connect: \n\t%v", err)
 	}
 	defer conn.Close()
-	client := proto.NewMidgardClient(conn)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	callback(ctx, client)
+	callback(ctx, proto.NewMidgardClient(conn))
 }
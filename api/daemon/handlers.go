@@ -13,9 +13,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"changkun.de/x/midgard/internal/clipboard"
 	"changkun.de/x/midgard/internal/config"
@@ -37,38 +40,29 @@ func (m *Daemon) Ping(ctx context.Context, in *proto.PingInput) (*proto.PingOutp
 // AllocateURL request the midgard server to allocate a given URL for
 // a given resource, or the content from the midgard universal clipboard.
 func (m *Daemon) AllocateURL(ctx context.Context, in *proto.AllocateURLInput) (*proto.AllocateURLOutput, error) {
-	var (
-		source = types.SourceUniversalClipboard
-		data   string
-		uri    string
-	)
-
+	req := m.clipboardAllocation()
 	if in.SourcePath != "" {
-		source = types.SourceAttachment
 		b, err := os.ReadFile(in.SourcePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read %v, err: %w", in.SourcePath, err)
 		}
-		data = base64.StdEncoding.EncodeToString(b)
-	}
-	if in.DesiredPath != "" {
-		// we want to make sure the extension of the file is correct
-		dext := filepath.Ext(in.DesiredPath)
-		sext := filepath.Ext(in.SourcePath)
-		uri = strings.TrimSuffix(in.DesiredPath, dext) + sext
-	}
-
-	res, err := utils.Request(
-		http.MethodPut,
-		types.EndpointAllocateURL,
-		&types.AllocateURLInput{
-			Source: source,
-			URI:    uri,
-			Data:   data,
-		})
+		req = &types.AllocateURLInput{
+			Source: types.SourceAttachment,
+			Data:   base64.StdEncoding.EncodeToString(b),
+		}
+	}
+	req.URI = desiredURI(in.DesiredPath, in.SourcePath)
+	req.TTL = in.TTL
+	req.MaxViews = int(in.MaxViews)
+	req.Password = in.Password
+
+	code, res, err := utils.RequestStatus(http.MethodPut, types.EndpointAllocateURL, req)
 	if err != nil {
 		return nil, fmt.Errorf("cannot perform allocate request, err %w", err)
 	}
+	if err := responseError(code, res); err != nil {
+		return nil, err
+	}
 	var out types.AllocateURLOutput
 	err = json.Unmarshal(res, &out)
 	if err != nil {
@@ -83,6 +77,16 @@ func (m *Daemon) AllocateURL(ctx context.Context, in *proto.AllocateURLInput) (*
 	return &proto.AllocateURLOutput{URL: url, Message: "Done."}, nil
 }
 
+// desiredURI returns the URI of a desired path for the given source
+// file, or empty if no path is desired.
+func desiredURI(dst, src string) string {
+	if dst == "" {
+		return ""
+	}
+	// we want to make sure the extension of the file is correct
+	return strings.TrimSuffix(dst, filepath.Ext(dst)) + filepath.Ext(src)
+}
+
 // CodeToImage tries to create an image for the given code.
 func (m *Daemon) CodeToImage(ctx context.Context, in *proto.CodeToImageInput) (out *proto.CodeToImageOutput, err error) {
 	log.Println("received a code2img request:", in.CodePath)
@@ -120,10 +124,21 @@ func (m *Daemon) CodeToImage(ctx context.Context, in *proto.CodeToImageInput) (o
 		}
 	}
 
-	res, err := utils.Request(http.MethodPost, types.EndpointCode2Image, &types.Code2ImgInput{Code: code})
+	// the server cannot read an end-to-end encrypted universal clipboard,
+	// so we send the code from the local clipboard instead.
+	if len(code) == 0 && m.cipher != nil {
+		if t, buf := clipboard.Local.Read(); t == types.MIMEPlainText {
+			code = utils.BytesToString(buf)
+		}
+	}
+
+	statusCode, res, err := utils.RequestStatus(http.MethodPost, types.EndpointCode2Image, &types.Code2ImgInput{Code: code})
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert: %w", err)
 	}
+	if err := responseError(statusCode, res); err != nil {
+		return nil, err
+	}
 
 	var o types.Code2ImgOutput
 	err = json.Unmarshal(res, &o)
@@ -172,3 +187,150 @@ func (m *Daemon) ListDaemons(ctx context.Context, in *proto.ListDaemonsInput) (o
 		}
 	}
 }
+
+// ListHistory lists the universal clipboard history.
+func (m *Daemon) ListHistory(ctx context.Context, in *proto.ListHistoryInput) (*proto.ListHistoryOutput, error) {
+	q := url.Values{}
+	if in.ID != 0 {
+		q.Set("id", strconv.FormatUint(in.ID, 10))
+	}
+	if in.Offset != 0 {
+		q.Set("offset", strconv.FormatInt(in.Offset, 10))
+	}
+	if in.Limit != 0 {
+		q.Set("limit", strconv.FormatInt(in.Limit, 10))
+	}
+
+	res, err := utils.Request(http.MethodGet, types.EndpointClipboardHistory+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot perform history request, err: %w", err)
+	}
+	var o types.GetClipboardHistoryOutput
+	err = json.Unmarshal(res, &o)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse history response, err: %w", err)
+	}
+	if o.Entries == nil {
+		return nil, fmt.Errorf("%s", o.Message)
+	}
+
+	out := &proto.ListHistoryOutput{Total: int64(o.Total)}
+	for _, e := range o.Entries {
+		// Show the plain data if the entry was encrypted by a daemon
+		// that shares the same key.
+		if d, err := m.decrypt(e.ClipboardData); err == nil {
+			e.ClipboardData = d
+		}
+
+		raw, err := e.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode history entry %d, err: %w", e.ID, err)
+		}
+
+		entry := &proto.HistoryEntry{
+			ID:     e.ID,
+			Time:   e.Time.Format(time.RFC3339),
+			Type:   string(e.Type),
+			Source: e.DaemonID,
+			Hash:   e.Hash,
+			Size:   int64(len(raw)),
+		}
+		// Only send non-text data if it was explicitly requested, a page
+		// of images can easily exceed the maximum gRPC message size.
+		if in.ID != 0 || e.Type.IsText() {
+			entry.Data = raw
+		}
+		out.Entries = append(out.Entries, entry)
+	}
+	return out, nil
+}
+
+// RestoreHistory puts an entry of the clipboard history back to the
+// universal clipboard.
+func (m *Daemon) RestoreHistory(ctx context.Context, in *proto.RestoreHistoryInput) (*proto.RestoreHistoryOutput, error) {
+	res, err := utils.Request(http.MethodPost, types.EndpointClipboardRestore,
+		&types.RestoreClipboardHistoryInput{ID: in.ID, DaemonID: m.ID})
+	if err != nil {
+		return nil, fmt.Errorf("cannot perform restore request, err: %w", err)
+	}
+	var o types.RestoreClipboardHistoryOutput
+	err = json.Unmarshal(res, &o)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse restore response, err: %w", err)
+	}
+	return &proto.RestoreHistoryOutput{Message: o.Message}, nil
+}
+
+// ListResources lists the allocated resources in a folder.
+func (m *Daemon) ListResources(ctx context.Context, in *proto.ListResourcesInput) (*proto.ListResourcesOutput, error) {
+	q := url.Values{}
+	q.Set("path", in.Path)
+	res, err := utils.Request(http.MethodGet, types.EndpointResources+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot perform list request, err: %w", err)
+	}
+	var o types.ListResourcesOutput
+	err = json.Unmarshal(res, &o)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse list response, err: %w", err)
+	}
+	if o.Resources == nil {
+		return nil, fmt.Errorf("%s", o.Message)
+	}
+
+	out := &proto.ListResourcesOutput{}
+	for _, r := range o.Resources {
+		resource := &proto.Resource{
+			Path:      r.Path,
+			URL:       config.Get().Domain + r.URL,
+			Dir:       r.Dir,
+			Size:      r.Size,
+			ModTime:   r.ModTime.Format(time.RFC3339),
+			MaxViews:  int64(r.MaxViews),
+			Views:     int64(r.Views),
+			Protected: r.Protected,
+		}
+		if !r.Expires.IsZero() {
+			resource.Expires = r.Expires.Format(time.RFC3339)
+		}
+		out.Resources = append(out.Resources, resource)
+	}
+	return out, nil
+}
+
+// DeleteResource deletes an allocated resource or an empty folder.
+func (m *Daemon) DeleteResource(ctx context.Context, in *proto.DeleteResourceInput) (*proto.DeleteResourceOutput, error) {
+	q := url.Values{}
+	q.Set("path", in.Path)
+	res, err := utils.Request(http.MethodDelete, types.EndpointResources+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot perform delete request, err: %w", err)
+	}
+	var o types.DeleteResourceOutput
+	err = json.Unmarshal(res, &o)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse delete response, err: %w", err)
+	}
+	return &proto.DeleteResourceOutput{Message: o.Message}, nil
+}
+
+// MoveResource moves an allocated resource to another path.
+func (m *Daemon) MoveResource(ctx context.Context, in *proto.MoveResourceInput) (*proto.MoveResourceOutput, error) {
+	res, err := utils.Request(http.MethodPost, types.EndpointResourcesMove,
+		&types.MoveResourceInput{From: in.From, To: in.To})
+	if err != nil {
+		return nil, fmt.Errorf("cannot perform move request, err: %w", err)
+	}
+	var o types.MoveResourceOutput
+	err = json.Unmarshal(res, &o)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse move response, err: %w", err)
+	}
+	if o.URL == "" {
+		return nil, fmt.Errorf("%s", o.Message)
+	}
+	return &proto.MoveResourceOutput{
+		URL:     config.Get().Domain + o.URL,
+		Message: o.Message,
+	}, nil
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/crypt"
 	"changkun.de/x/midgard/internal/types"
 	"changkun.de/x/midgard/internal/types/proto"
 	"changkun.de/x/midgard/internal/utils"
@@ -31,6 +32,10 @@ type Daemon struct {
 	ws          *websocket.Conn
 	readChs     sync.Map                     // {string: chan *types.WebsocketMessage}
 	writeCh     chan *types.WebsocketMessage // writeCh is used for sending message along ws.
+	cipher      *crypt.Cipher                // nil if end-to-end encryption is disabled.
+	uploads     sync.Map                     // {string: string}, IDs of resumable uploads by file.
+	appliedMu   sync.Mutex
+	applied     []types.Representation // the clipboard data that was last applied from the server.
 
 	proto.UnimplementedMidgardServer
 }
@@ -44,10 +49,18 @@ func NewDaemon() *Daemon {
 			panic(fmt.Errorf("failed to initialize daemon: %v", err))
 		}
 	}
+	var c *crypt.Cipher
+	if key := config.D().Key; key != "" {
+		c, err = crypt.New(key)
+		if err != nil {
+			panic(fmt.Errorf("failed to initialize daemon encryption: %v", err))
+		}
+	}
 	return &Daemon{
 		ID:          id,
 		forceUpdate: make(chan struct{}, 1),
 		writeCh:     make(chan *types.WebsocketMessage, 10),
+		cipher:      c,
 	}
 }
 
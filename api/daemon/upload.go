@@ -0,0 +1,176 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"changkun.de/x/midgard/internal/clipboard"
+	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/types/proto"
+	"changkun.de/x/midgard/internal/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Upload uploads a file in chunks to the midgard server and allocates
+// a URL for it. The first message describes the file, and is replied
+// with the offset to continue from, which is non-zero if a previous
+// upload of the same file was interrupted. Each following chunk is
+// forwarded to the server and replied with the uploaded bytes.
+func (m *Daemon) Upload(stream proto.Midgard_UploadServer) error {
+	in, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("cannot receive upload header, err: %w", err)
+	}
+	if in.Alloc == nil || in.Alloc.SourcePath == "" {
+		return errors.New("missing file to upload")
+	}
+	src, err := filepath.Abs(in.Alloc.SourcePath)
+	if err != nil {
+		return fmt.Errorf("invalid file path, err: %w", err)
+	}
+	key := fmt.Sprintf("%s:%d:%d:%s:%s:%d:%s", src, in.Size, in.ModTime,
+		in.Alloc.DesiredPath, in.Alloc.TTL, in.Alloc.MaxViews, in.Alloc.Password)
+
+	id, off, err := m.resumeUpload(key)
+	if err != nil {
+		id, off, err = m.createUpload(in)
+		if err != nil {
+			return err
+		}
+		m.uploads.Store(key, id)
+	}
+	err = stream.Send(&proto.UploadOutput{Offset: off, Message: "uploading"})
+	if err != nil {
+		return fmt.Errorf("cannot send upload offset, err: %w", err)
+	}
+
+	for {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return errors.New("upload is incomplete, please retry to resume")
+		}
+		if err != nil {
+			return fmt.Errorf("cannot receive upload data, err: %w", err)
+		}
+
+		code, res, err := utils.RequestRaw(http.MethodPatch, types.EndpointUploads+"/"+id, http.Header{
+			types.HeaderUploadOffset: []string{strconv.FormatInt(off, 10)},
+			"Content-Type":           []string{"application/octet-stream"},
+		}, bytes.NewReader(in.Data))
+		if err != nil {
+			return fmt.Errorf("cannot perform upload request, err: %w", err)
+		}
+		if err := responseError(code, res); err != nil {
+			return err
+		}
+		var out types.UploadOutput
+		err = json.Unmarshal(res, &out)
+		if err != nil {
+			return fmt.Errorf("cannot parse upload response, err: %w", err)
+		}
+		if out.URL != "" {
+			m.uploads.Delete(key)
+			url := config.Get().Domain + out.URL
+			clipboard.Local.Write(types.MIMEPlainText, utils.StringToBytes(url))
+			return stream.Send(&proto.UploadOutput{Offset: out.Offset, URL: url, Message: "Done."})
+		}
+		if out.Offset != off+int64(len(in.Data)) {
+			return fmt.Errorf("%s", out.Message)
+		}
+		off = out.Offset
+		err = stream.Send(&proto.UploadOutput{Offset: off})
+		if err != nil {
+			return fmt.Errorf("cannot send upload progress, err: %w", err)
+		}
+	}
+}
+
+// resumeUpload returns the ID and the offset of an interrupted upload
+// of the given key.
+func (m *Daemon) resumeUpload(key string) (string, int64, error) {
+	v, ok := m.uploads.Load(key)
+	if !ok {
+		return "", 0, errors.New("no upload to resume")
+	}
+	id := v.(string)
+	res, err := utils.Request(http.MethodGet, types.EndpointUploads+"/"+id, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	var out types.UploadOutput
+	err = json.Unmarshal(res, &out)
+	if err != nil {
+		return "", 0, err
+	}
+	if out.ID != id {
+		// the upload is gone on the server, e.g. expired.
+		m.uploads.Delete(key)
+		return "", 0, errors.New(out.Message)
+	}
+	return id, out.Offset, nil
+}
+
+// createUpload creates a new upload on the server for the given header.
+func (m *Daemon) createUpload(in *proto.UploadInput) (string, int64, error) {
+	req := types.CreateUploadInput{
+		AllocateURLInput: types.AllocateURLInput{
+			Source:   types.SourceAttachment,
+			URI:      desiredURI(in.Alloc.DesiredPath, in.Alloc.SourcePath),
+			TTL:      in.Alloc.TTL,
+			MaxViews: int(in.Alloc.MaxViews),
+			Password: in.Alloc.Password,
+		},
+		Size: in.Size,
+	}
+	code, res, err := utils.RequestStatus(http.MethodPost, types.EndpointUploads, req)
+	if err != nil {
+		return "", 0, fmt.Errorf("cannot perform upload request, err: %w", err)
+	}
+	if err := responseError(code, res); err != nil {
+		return "", 0, err
+	}
+	var out types.UploadOutput
+	err = json.Unmarshal(res, &out)
+	if err != nil {
+		return "", 0, fmt.Errorf("cannot parse upload response, err: %w", err)
+	}
+	if out.ID == "" {
+		return "", 0, fmt.Errorf("%s", out.Message)
+	}
+	return out.ID, out.Offset, nil
+}
+
+// responseError returns the error of an unsuccessful response of the
+// server, or nil if the response is successful. Responses of limits are
+// mapped to gRPC errors, their body may not even come from midgard, e.g.
+// a proxy that rejects a large request.
+func responseError(code int, res []byte) error {
+	if code < http.StatusBadRequest {
+		return nil
+	}
+	var out struct {
+		Message string `json:"msg"`
+	}
+	if json.Unmarshal(res, &out) != nil || out.Message == "" {
+		out.Message = fmt.Sprintf("server responded %d %s", code, http.StatusText(code))
+	}
+	switch code {
+	case http.StatusRequestEntityTooLarge:
+		return status.Errorf(codes.InvalidArgument, "data is too large for the server: %s", out.Message)
+	case http.StatusInsufficientStorage:
+		return status.Errorf(codes.ResourceExhausted, "storage quota is exhausted: %s", out.Message)
+	}
+	return errors.New(out.Message)
+}
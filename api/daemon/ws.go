@@ -6,7 +6,6 @@ package daemon
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -26,9 +25,7 @@ func (m *Daemon) wsConnect() error {
 	defer m.Unlock()
 
 	// connect to midgard server via websocket
-	creds := config.Get().Server.Auth.User + ":" + config.Get().Server.Auth.Pass
-	token := base64.StdEncoding.EncodeToString(utils.StringToBytes(creds))
-	h := http.Header{"Authorization": {"Basic " + token}}
+	h := http.Header{"Authorization": {utils.Authorization()}}
 
 	api := types.EndpointSubscribe
 	if strings.Contains(config.Get().Domain, "localhost") || strings.Contains(config.Get().Domain, "0.0.0.0") {
@@ -44,11 +41,12 @@ func (m *Daemon) wsConnect() error {
 
 	m.ws = conn
 
-	// handshake with midgard server
+	// handshake with midgard server, and join the configured channels
+	hs, _ := json.Marshal(types.HandshakeData{Channels: config.D().Channels})
 	err = m.ws.WriteMessage(websocket.BinaryMessage, (&types.WebsocketMessage{
 		Action: types.ActionHandshakeRegister,
 		UserID: m.ID,
-		Data:   nil,
+		Data:   hs,
 	}).Encode())
 	if err != nil {
 		return fmt.Errorf("failed to send handshake message: %w", err)
@@ -70,6 +68,13 @@ func (m *Daemon) wsConnect() error {
 			m.ID = wsm.UserID // update local id if user id is updated
 			log.Println("conflict hostname, updated daemon id: ", m.ID)
 		}
+		var joined types.HandshakeData
+		if json.Unmarshal(wsm.Data, &joined) == nil && len(joined.Channels) > 0 {
+			log.Println("joined channels:", strings.Join(joined.Channels, ", "))
+		}
+		if wsm.Message != "" {
+			log.Println("cannot join channels:", wsm.Message)
+		}
 	default:
 		conn.Close() // close the connection if handshake is not ready
 		return fmt.Errorf("failed to handshake with midgard server: %w", err)
@@ -149,20 +154,32 @@ func (m *Daemon) readFromServer(ctx context.Context) {
 					log.Printf("failed to parse clipboard data: %v", err)
 					continue
 				}
-				var raw []byte
-				if d.Type == types.MIMEImagePNG {
-					// We assume the server send us a base64 encoded image data,
-					// Let's decode it into bytes.
-					raw, err = base64.StdEncoding.DecodeString(d.Data)
-					if err != nil {
-						raw = []byte{}
-					}
-				} else {
-					raw = utils.StringToBytes(d.Data)
+				d, err = m.decrypt(d)
+				if err != nil {
+					log.Printf("failed to decrypt clipboard data: %v", err)
+					continue
+				}
+				reps, err := d.Decode()
+				if err != nil {
+					log.Printf("failed to decode clipboard data: %v", err)
+					continue
 				}
 
-				log.Printf("universal clipboard has changed from %s, type: %s, sync with local...", wsm.UserID, d.Type)
-				clipboard.Local.Write(d.Type, raw) // change local clipboard
+				if wsm.Channel != "" {
+					log.Printf("channel %s has changed from %s, type: %s, sync with local...", wsm.Channel, wsm.UserID, d.Type)
+				} else {
+					log.Printf("universal clipboard has changed from %s, type: %s, sync with local...", wsm.UserID, d.Type)
+				}
+				// remember the data as they are written to the local
+				// clipboard, e.g. images are converted to PNG, before
+				// the local clipboard sees the change.
+				reps = clipboard.Writable(reps...)
+				if len(reps) == 0 {
+					log.Printf("local clipboard does not support %s data, ignored.", d.Type)
+					continue
+				}
+				m.apply(reps)
+				clipboard.Local.WriteAll(reps...) // change local clipboard
 			}
 		}
 	}
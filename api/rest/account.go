@@ -0,0 +1,69 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"log"
+
+	"changkun.de/x/midgard/internal/clipboard"
+	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/namespace"
+	"github.com/gin-gonic/gin"
+)
+
+// account is the server side state of an authenticated midgard user.
+type account struct {
+	name  string
+	admin bool // the user of auth.user administrates the server
+	// namespace is the URL namespace of allocated resources. It is empty
+	// for the user of auth.user, which owns the root namespace, and
+	// /~<user> for any additional users.
+	namespace string
+	clipboard clipboard.UniversalClipboard
+}
+
+// newAccounts creates the accounts of all configured midgard users. It
+// fails if a user has an invalid or a duplicated name, since the name
+// is part of the paths of the namespace and the logs of the user.
+func newAccounts() map[string]*account {
+	accounts := map[string]*account{}
+	for i, u := range config.S().Users() {
+		if _, ok := accounts[u.Name]; ok {
+			log.Fatalf("duplicated user: %s", u.Name)
+		}
+		if i == 0 {
+			accounts[u.Name] = &account{
+				name:      u.Name,
+				admin:     true,
+				clipboard: newClipboard(logsPath),
+			}
+			continue
+		}
+		ns, err := namespace.User(u.Name)
+		if err != nil {
+			log.Fatalf("invalid user: %v", err)
+		}
+		accounts[u.Name] = &account{
+			name:      u.Name,
+			namespace: ns,
+			clipboard: newClipboard(logsPath + "/users/" + u.Name),
+		}
+	}
+	return accounts
+}
+
+// account returns the account of the authenticated user.
+func (m *Midgard) account(c *gin.Context) *account {
+	return m.accounts[c.GetString("midgard_user")]
+}
+
+// credentials returns the basic auth credentials of all accounts.
+func credentials() Credentials {
+	creds := Credentials{}
+	for _, u := range config.S().Users() {
+		creds[u.Name] = u.Pass
+	}
+	return creds
+}
@@ -9,10 +9,11 @@ import (
 	"log"
 	"net/http"
 	"strconv"
-	"sync"
-	"sync/atomic"
+	"strings"
 	"time"
 
+	"changkun.de/x/midgard/internal/blocklist"
+	"changkun.de/x/midgard/internal/token"
 	"changkun.de/x/midgard/internal/utils"
 	"github.com/gin-gonic/gin"
 )
@@ -38,71 +39,41 @@ func (a authPairs) searchCredential(authValue string) (string, bool) {
 	return "", false
 }
 
-// blocklist holds the ip that should be blocked for further requests.
-//
-// FIXME: this map may keep grow without releasing memory because of
-// continuously attempts. we also do not persist this type of block info
-// to the disk, which means if we reboot the service then all the blocker
-// are gone and they can attack the server again.
-var blocklist sync.Map // map[string]*blockinfo{}
-
-type blockinfo struct {
-	failCount int64
-	lastFail  atomic.Value // time.Time
-	blockTime atomic.Value // time.Duration
-}
-
-const maxFailureAttempts = 5
-
 // Credentials is the basic auth authentication credentials
 type Credentials map[string]string
 
 // BasicAuthWithAttemptsControl offers basic auth with maximum failure control.
-func BasicAuthWithAttemptsControl(creds Credentials) gin.HandlerFunc {
+// IPs that fail too often are blocked by the given blocklist. If tokens is
+// not nil, API tokens are accepted as bearer authentication as well, see
+// requireScopes.
+func BasicAuthWithAttemptsControl(creds Credentials, tokens *token.Store, bl *blocklist.Blocklist) gin.HandlerFunc {
 	realm := "Basic realm=" + strconv.Quote("Authorization Required")
 	pairs := processCreds(creds)
 	return func(c *gin.Context) {
 		// check if the IP failure attempts are too much
 		// if so, direct abort the request without checking credentials
 		ip := c.ClientIP()
-		if i, ok := blocklist.Load(ip); ok {
-			info := i.(*blockinfo)
-			count := atomic.LoadInt64(&info.failCount)
-			if count > maxFailureAttempts {
-				// if the ip is under block, then directly abort
-				last := info.lastFail.Load().(time.Time)
-				bloc := info.blockTime.Load().(time.Duration)
-
-				if time.Now().UTC().Sub(last.Add(bloc)) < 0 {
-					log.Printf("block ip %v, block time: %v, release until: %v\n",
-						ip, bloc, last.Add(bloc))
-					c.AbortWithStatus(http.StatusForbidden)
-					return
-				}
-
-				// clear the failcount, but increase the next block time
-				atomic.StoreInt64(&info.failCount, 0)
-				info.blockTime.Store(bloc * 2)
-			}
-
+		if until, blocked := bl.Blocked(ip, time.Now().UTC()); blocked {
+			log.Printf("block ip %v, release until: %v\n", ip, until)
+			c.AbortWithStatus(http.StatusForbidden)
+			return
 		}
 
 		// Search user in the slice of allowed credentials
-		user, found := pairs.searchCredential(c.Request.Header.Get("Authorization"))
-		if !found {
-			if i, ok := blocklist.Load(ip); !ok {
-				info := &blockinfo{
-					failCount: 1,
-				}
-				info.lastFail.Store(time.Now().UTC())
-				info.blockTime.Store(time.Second * 10)
-
-				blocklist.Store(ip, info)
-			} else {
-				info := i.(*blockinfo)
-				atomic.AddInt64(&info.failCount, 1)
-				info.lastFail.Store(time.Now().UTC())
+		authValue := c.Request.Header.Get("Authorization")
+		user, found := pairs.searchCredential(authValue)
+
+		// Otherwise search the token of an existing user
+		var tk token.Token
+		if !found && tokens != nil && strings.HasPrefix(authValue, "Bearer ") {
+			tk, found = tokens.Lookup(strings.TrimPrefix(authValue, "Bearer "))
+			if _, ok := creds[tk.User]; !ok {
+				found = false
 			}
+			user = tk.User
+		}
+		if !found {
+			bl.Fail(ip, time.Now().UTC())
 
 			// Credentials doesn't match, we return 401 and abort handlers chain.
 			c.Header("WWW-Authenticate", realm)
@@ -113,6 +84,9 @@ func BasicAuthWithAttemptsControl(creds Credentials) gin.HandlerFunc {
 		// The user credentials was found, set user's id to key
 		// in this context.
 		c.Set("midgard_user", user)
+		if tk.ID != "" {
+			c.Set("midgard_token", tk)
+		}
 	}
 }
 
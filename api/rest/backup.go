@@ -0,0 +1,320 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"changkun.de/x/midgard/internal/backup"
+	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/lockfile"
+	"changkun.de/x/midgard/internal/resource"
+	"changkun.de/x/midgard/internal/storage"
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/version"
+	"github.com/gin-gonic/gin"
+)
+
+// newBackup returns the configured backup strategy of the repo, the
+// files that are excluded from the backup are given by exclude.
+func newBackup(exclude func(rel string) bool) (backup.Backup, error) {
+	conf := config.S().Store.Backup
+	switch conf.Strategy {
+	case "", "git":
+		return &backup.Git{
+			Dir:      config.RepoPath,
+			Remote:   conf.Repo,
+			Template: "./data/template",
+			Exclude:  exclude,
+		}, nil
+	case "tarball":
+		dest := conf.Dir
+		if dest == "" {
+			dest = "./data/backups"
+		}
+		return &backup.Tarball{
+			Dir:     config.RepoPath,
+			Dest:    dest,
+			Keep:    conf.Keep,
+			Exclude: exclude,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown backup strategy: %s", conf.Strategy)
+	}
+}
+
+// backupLogs is the folder inside the repo where the clipboard logs are
+// mirrored before each backup if server.store.backup.logs is enabled, so
+// they are backed up and restored along with the repo. It is internal
+// and never served.
+const backupLogs = metaDir + "/logs/clipboard"
+
+// mirrorLogs mirrors the clipboard logs to the repo, or removes their
+// mirror if the clipboard logs are not backed up.
+func mirrorLogs() error {
+	dst := config.RepoPath + backupLogs
+	if !config.S().Store.Backup.Logs {
+		err := os.RemoveAll(dst)
+		if err != nil {
+			return fmt.Errorf("cannot remove mirror of clipboard logs: %w", err)
+		}
+		return nil
+	}
+	changes, err := backup.Diff(dst, logsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // no clipboard logs yet
+	}
+	if err == nil {
+		err = backup.Apply(dst, logsPath, changes)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot mirror clipboard logs: %w", err)
+	}
+	return nil
+}
+
+// backupIndex is the folder inside the repo where a copy of the resource
+// index is written before each backup. The copy only has the metadata
+// of the backed up resources and no password hashes, the index itself
+// is never backed up.
+const backupIndex = metaDir + "/backup"
+
+// exportIndex writes the copy of the resource index for the backup.
+func (m *Midgard) exportIndex() error {
+	err := m.resources.Export(m.repo, backupIndex, backedUp)
+	if err != nil {
+		return fmt.Errorf("cannot export resource index: %w", err)
+	}
+	return nil
+}
+
+// backedUp reports whether the resource of the given metadata is backed
+// up. Ephemeral resources are never backed up, and neither are password
+// protected resources, whose password would not protect the backup.
+func backedUp(meta resource.Meta) bool {
+	return !meta.Ephemeral() && !meta.Protected()
+}
+
+// backup backups the repo periodically until the context is canceled.
+// It runs in the background of the server, failures are logged and
+// retried at the next interval.
+func (m *Midgard) backup(ctx context.Context) {
+	if !config.S().Store.Backup.Enable {
+		log.Println("backup feature is disabled.")
+		return
+	}
+	if _, ok := m.repo.(storage.Local); !ok {
+		log.Println("backup is not available for the storage backend.")
+		return
+	}
+	index := m.resources.Files()
+	b, err := newBackup(func(rel string) bool {
+		for _, f := range index {
+			if rel == f {
+				return true
+			}
+		}
+		meta, ok := m.resources.Get(rel)
+		return ok && !backedUp(meta)
+	})
+	if err != nil {
+		log.Printf("backup feature is disabled: %v", err)
+		return
+	}
+	strategy := config.S().Store.Backup.Strategy
+	if strategy == "" {
+		strategy = "git"
+	}
+	m.backups.Start(strategy)
+
+	interval := time.Duration(config.S().Store.Backup.Interval) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	ready := false
+	for {
+		var (
+			now = time.Now()
+			rev string
+			err error
+		)
+		if !ready {
+			err = b.Init(ctx)
+			if err != nil {
+				err = fmt.Errorf("cannot initialize backup: %w", err)
+			} else {
+				ready = true
+				log.Println("backup is enabled.")
+			}
+		}
+		if ready {
+			err = mirrorLogs()
+		}
+		if ready && err == nil {
+			err = m.exportIndex()
+		}
+		if ready && err == nil {
+			rev, err = b.Run(ctx, now)
+			if err != nil {
+				err = fmt.Errorf("cannot backup your data: %w", err)
+			} else {
+				log.Printf("midgard: backup at %s, revision %s", now.Format("2006-01-02 15:04"), rev)
+			}
+		}
+		if err != nil {
+			log.Println(err)
+		}
+		if ctx.Err() != nil {
+			return // the failure is caused by the shutdown
+		}
+		if err := m.backups.Record(ctx, now.UTC(), rev, err); err != nil {
+			log.Printf("failed to notify backup status: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Restore restores the repo and the clipboard logs from the configured
+// backup at the given point, a revision or a time, and returns the
+// revision and the changes. Changes of the clipboard logs are given by
+// their paths in the backup, see backupLogs. The clipboard logs are
+// kept if the backup does not contain them, e.g. an earlier backup. If
+// dryRun is true, nothing is changed. The data are not restored while
+// the server is running.
+func Restore(ctx context.Context, at string, dryRun bool) (string, []backup.Change, error) {
+	if b := config.S().Store.Backend; b != "" && b != "fs" {
+		return "", nil, fmt.Errorf("backup is not available for the %s storage backend", b)
+	}
+	if !dryRun {
+		unlock, err := lockfile.Lock(serverLock)
+		if errors.Is(err, lockfile.ErrLocked) {
+			return "", nil, errors.New("the server is running, stop it before restoring")
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		defer unlock()
+	}
+	b, err := newBackup(nil)
+	if err != nil {
+		return "", nil, err
+	}
+	r, ok := b.(backup.Restorer)
+	if !ok {
+		return "", nil, errors.New("backup strategy cannot be restored")
+	}
+
+	err = os.MkdirAll(dataPath, fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot create data folder: %w", err)
+	}
+	tmp, err := os.MkdirTemp(dataPath, ".restore-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot create temporary folder: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	rev, err := r.Checkout(ctx, at, tmp)
+	if err != nil {
+		return "", nil, err
+	}
+	err = restoreIndex(tmp)
+	if err != nil {
+		return "", nil, err
+	}
+	changes, err := backup.Diff(config.RepoPath, tmp)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot compare backup: %w", err)
+	}
+	logs, err := backup.Diff(logsPath, tmp+backupLogs)
+	if errors.Is(err, fs.ErrNotExist) {
+		logs, err = nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot compare backup of clipboard logs: %w", err)
+	}
+
+	// the mirror of the clipboard logs in the repo may be outdated, the
+	// changes of the logs are listed instead.
+	var all []backup.Change
+	for _, c := range changes {
+		if c.Path != backupLogs && !strings.HasPrefix(c.Path, backupLogs+"/") {
+			all = append(all, c)
+		}
+	}
+	for _, c := range logs {
+		all = append(all, backup.Change{Op: c.Op, Path: backupLogs + c.Path})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Path < all[j].Path })
+	if dryRun {
+		return rev, all, nil
+	}
+	err = backup.Apply(config.RepoPath, tmp, changes)
+	if err == nil {
+		err = backup.Apply(logsPath, tmp+backupLogs, logs)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return rev, all, nil
+}
+
+// restoreIndex restores the resource index of the checked out backup in
+// the given folder from its copy, see backupIndex, or from the index of
+// earlier backups that have no copy. Metadata of resources that are not
+// in the backup are dropped, e.g. of protected resources.
+func restoreIndex(dir string) error {
+	s := storage.NewFS(dir)
+	from := backupIndex
+	if _, err := s.Stat(backupIndex); errors.Is(err, fs.ErrNotExist) {
+		from = metaDir
+	}
+	idx, err := resource.Open(s, from)
+	if err == nil {
+		err = idx.Export(s, metaDir, func(meta resource.Meta) bool {
+			info, err := s.Stat(meta.Path)
+			return err == nil && !info.Dir
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("cannot restore resource index: %w", err)
+	}
+	return nil
+}
+
+// Status returns the status of the server and its backups.
+func (m *Midgard) Status(c *gin.Context) {
+	s := m.backups.Status()
+	c.JSON(http.StatusOK, types.StatusOutput{
+		Version: version.GitVersion,
+		Backup: types.BackupStatus{
+			Enabled:     s.Strategy != "",
+			Strategy:    s.Strategy,
+			LastSuccess: s.LastSuccess,
+			LastFailure: s.LastFailure,
+			Revision:    s.Revision,
+			Error:       s.Error,
+			Failures:    s.Failures,
+		},
+		Message: "success.",
+	})
+}
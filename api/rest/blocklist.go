@@ -0,0 +1,65 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"changkun.de/x/midgard/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// requireAdmin rejects requests that are not from the administrator.
+func (m *Midgard) requireAdmin(c *gin.Context) {
+	if a := m.account(c); a == nil || !a.admin {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"msg": "this request requires the administrator.",
+		})
+	}
+}
+
+// ListBlocklist lists all IPs that failed to authenticate, the most
+// recent failure comes first.
+func (m *Midgard) ListBlocklist(c *gin.Context) {
+	limits := m.blocked.Limits()
+	out := types.ListBlocklistOutput{
+		Entries: []types.BlockedIP{},
+		Message: "success.",
+	}
+	for _, e := range m.blocked.List() {
+		out.Entries = append(out.Entries, types.BlockedIP{
+			IP:           e.IP,
+			Failures:     e.Failures,
+			LastFailure:  e.LastFail,
+			BlockedUntil: e.BlockedUntil(limits),
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ClearBlocklist removes the IP of the ip query from the blocklist, or
+// all IPs if no IP is given.
+func (m *Midgard) ClearBlocklist(c *gin.Context) {
+	ip := c.Query("ip")
+	if !m.blocked.Clear(ip) {
+		c.JSON(http.StatusNotFound, types.ClearBlocklistOutput{
+			Message: fmt.Sprintf("%s is not in the blocklist.", ip),
+		})
+		return
+	}
+	if err := m.blocked.Save(); err != nil {
+		c.JSON(http.StatusInternalServerError, types.ClearBlocklistOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	msg := "blocklist is cleared."
+	if ip != "" {
+		msg = fmt.Sprintf("%s is removed from the blocklist.", ip)
+	}
+	c.JSON(http.StatusOK, types.ClearBlocklistOutput{Message: msg})
+}
@@ -0,0 +1,65 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"fmt"
+	"log"
+
+	"changkun.de/x/midgard/internal/clipboard"
+	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/namespace"
+)
+
+// channel is a named universal clipboard that is shared by its members.
+type channel struct {
+	config.Channel
+	clipboard clipboard.UniversalClipboard
+}
+
+// newChannels creates all configured channels. It fails if a channel
+// has an invalid or a duplicated name, since the name is part of the
+// path of the logs of the channel.
+func newChannels() map[string]*channel {
+	channels := map[string]*channel{}
+	for _, c := range config.S().Channels {
+		if err := namespace.CheckName(c.Name); err != nil {
+			log.Fatalf("invalid channel: %v", err)
+		}
+		if _, ok := channels[c.Name]; ok {
+			log.Fatalf("duplicated channel: %s", c.Name)
+		}
+		channels[c.Name] = &channel{
+			Channel:   c,
+			clipboard: newClipboard(logsPath + "/channels/" + c.Name),
+		}
+	}
+	return channels
+}
+
+// join returns the channel of the given name if the account is a member.
+func (m *Midgard) join(a *account, name string) (*channel, error) {
+	ch, ok := m.channels[name]
+	if !ok {
+		return nil, fmt.Errorf("channel %s does not exist", name)
+	}
+	if !ch.IsMember(a.name) {
+		return nil, fmt.Errorf("%s is not a member of channel %s", a.name, name)
+	}
+	return ch, nil
+}
+
+// clipboard returns the private universal clipboard of the account if
+// the given channel is empty, or the clipboard of the channel otherwise.
+func (m *Midgard) clipboard(a *account, channel string) (clipboard.UniversalClipboard, error) {
+	if channel == "" {
+		return a.clipboard, nil
+	}
+	ch, err := m.join(a, channel)
+	if err != nil {
+		return nil, err
+	}
+	return ch.clipboard, nil
+}
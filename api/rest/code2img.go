@@ -5,15 +5,22 @@
 package rest
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"html/template"
 	"io/fs"
+	"log"
 	"net/http"
-	"os"
+	"net/url"
 	"os/exec"
+	"path"
+	"strings"
 	"time"
 
-	"changkun.de/x/midgard/internal/clipboard"
 	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/gallery"
+	"changkun.de/x/midgard/internal/token"
 	"changkun.de/x/midgard/internal/types"
 	"changkun.de/x/midgard/internal/utils"
 	"github.com/gin-gonic/gin"
@@ -59,8 +66,9 @@ const code2imgTimeFormat = "060102-150405"
 // Code2img code to image handler
 func (m *Midgard) Code2img(c *gin.Context) {
 	var in types.Code2ImgInput
+	m.limitBody(c)
 	if err := c.ShouldBindJSON(&in); err != nil {
-		c.JSON(http.StatusBadRequest, &types.Code2ImgOutput{
+		c.JSON(bindStatus(err), &types.Code2ImgOutput{
 			Message: "bad inputs",
 		})
 		return
@@ -68,11 +76,21 @@ func (m *Midgard) Code2img(c *gin.Context) {
 
 	// if the request does not send any code, then let's use the data
 	// from our universal clipboard
+	a := m.account(c)
 	if len(in.Code) == 0 {
-		in.Code = utils.BytesToString(clipboard.Universal.ReadAs(types.MIMEPlainText))
+		if !requireScope(c, token.ScopeClipboardRead) {
+			return
+		}
+		in.Code = utils.BytesToString(a.clipboard.ReadAs(types.MIMEPlainText))
 	}
 
 	// double check, if the code is still empty, then we don't want do anything
+	if len(in.Code) == 0 && a.clipboard.ReadAs(types.MIMEEncrypted) != nil {
+		c.JSON(http.StatusBadRequest, &types.Code2ImgOutput{
+			Message: "universal clipboard is end-to-end encrypted, send your code in the request",
+		})
+		return
+	}
 	if len(in.Code) == 0 {
 		c.JSON(http.StatusBadRequest, &types.Code2ImgOutput{
 			Message: "no code neither in your request or clipboard",
@@ -81,11 +99,20 @@ func (m *Midgard) Code2img(c *gin.Context) {
 	}
 
 	// save the code
-	id := time.Now().UTC().Format(code2imgTimeFormat)
-	codefile := "/code/" + id // no extension! we don't care which language is using.
+	now := time.Now().UTC()
+	id := now.Format(code2imgTimeFormat)
+	codedir := a.namespace + "/code/"
+	codefile := codedir + id // no extension! we don't care which language is using.
 
-	err := os.WriteFile(config.RepoPath+codefile, utils.StringToBytes(in.Code), fs.ModePerm)
+	if err := m.quota.Reserve(a.name, int64(len(in.Code))); err != nil {
+		c.JSON(quotaStatus(err), &types.Code2ImgOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	err := m.repo.Put(codefile, strings.NewReader(in.Code))
 	if err != nil {
+		m.quota.Release(a.name, int64(len(in.Code)))
 		c.JSON(http.StatusBadRequest, &types.Code2ImgOutput{
 			Message: fmt.Sprintf("failed to save your code: %v", err),
 		})
@@ -95,24 +122,247 @@ func (m *Midgard) Code2img(c *gin.Context) {
 	// render and save the image
 	imgb, err := code2img.Render(c.Request.Context(), code2img.LangAuto, in.Code)
 	if err != nil {
+		m.logRemoveResource(codefile)
 		c.JSON(http.StatusBadRequest, &types.Code2ImgOutput{
 			Message: fmt.Sprintf("failed to render code image: %v", err),
 		})
 		return
 	}
 
-	imgfile := "/code/" + id + ".png"
-	err = os.WriteFile(config.RepoPath+imgfile, imgb, fs.ModePerm)
+	imgfile := codedir + id + ".png"
+	if err := m.quota.Reserve(a.name, int64(len(imgb))); err != nil {
+		m.logRemoveResource(codefile)
+		c.JSON(quotaStatus(err), &types.Code2ImgOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	err = m.repo.Put(imgfile, bytes.NewReader(imgb))
 	if err != nil {
+		m.quota.Release(a.name, int64(len(imgb)))
+		m.logRemoveResource(codefile)
 		c.JSON(http.StatusBadRequest, &types.Code2ImgOutput{
 			Message: fmt.Sprintf("failed to save your image: %v", err),
 		})
 		return
 	}
 
+	if x, ok := m.codes[a.name]; ok {
+		x.Add(gallery.Snippet{ID: id, Time: now.Truncate(time.Second), Code: in.Code})
+	}
 	c.JSON(http.StatusOK, &types.Code2ImgOutput{
 		Code:    config.S().Store.Prefix + codefile,
 		Image:   config.S().Store.Prefix + imgfile,
 		Message: "render success",
 	})
 }
+
+const (
+	codePageSize    = 20
+	maxCodePageSize = 100
+)
+
+var codeTmpl = template.Must(template.New("codes").Parse(`<form method="get">
+<input name="q" value="{{ .Query }}" placeholder="search code">
+<button type="submit">Search</button>
+</form>
+<pre>{{ .Total }} codes{{ if .Query }} matching "{{ .Query }}"{{ end }}, page {{ .Page }} of {{ .Pages }}</pre>
+{{ range .Codes }}
+<pre>--- {{ .TimeFmt }} ---: <a href="{{ .ImageURL }}">Image</a>, <a href="{{ .TextURL }}">Text</a></pre>
+<pre>
+{{ .Code }}
+</pre>
+{{ end }}
+<pre>{{ if .Newer }}<a href="{{ .Newer }}">newer</a>{{ end }} {{ if .Older }}<a href="{{ .Older }}">older</a>{{ end }}</pre>
+`))
+
+// newGalleries creates the code galleries of all accounts.
+func (m *Midgard) newGalleries() map[string]*gallery.Index {
+	codes := map[string]*gallery.Index{}
+	for name, a := range m.accounts {
+		dir := a.namespace + "/code"
+		codes[name] = gallery.New(func() ([]gallery.Snippet, error) {
+			return m.loadCodes(dir)
+		})
+	}
+	return codes
+}
+
+// loadCodes loads the codes in the given folder of the repo.
+func (m *Midgard) loadCodes(dir string) ([]gallery.Snippet, error) {
+	infos, err := m.repo.List(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snippets []gallery.Snippet
+	for _, info := range infos {
+		file := info.Name()
+		if info.Dir || path.Ext(file) != "" {
+			continue
+		}
+		t, err := time.Parse(code2imgTimeFormat, file)
+		if err != nil {
+			continue // not a code of code2img
+		}
+		b, err := m.read(info.Key)
+		if err != nil {
+			return snippets, err
+		}
+		snippets = append(snippets, gallery.Snippet{ID: file, Time: t, Code: string(b)})
+	}
+	return snippets, nil
+}
+
+// searchCodes searches the codes of the gallery of the given account.
+// It returns the codes of the requested page and the number of all
+// matches, the page and page size of the input fall back to defaults.
+func (m *Midgard) searchCodes(a *account, in *types.ListCodeInput) ([]types.CodeInfo, int) {
+	if in.Page < 1 {
+		in.Page = 1
+	}
+	if in.Size < 1 {
+		in.Size = codePageSize
+	}
+	in.Size = min(in.Size, maxCodePageSize)
+	x, ok := m.codes[a.name]
+	if !ok {
+		return []types.CodeInfo{}, 0
+	}
+	snippets, total := x.Search(in.Query, in.Page, in.Size)
+	codes := make([]types.CodeInfo, 0, len(snippets))
+	for _, s := range snippets {
+		file := config.S().Store.Prefix + a.namespace + "/code/" + s.ID
+		codes = append(codes, types.CodeInfo{
+			ID:    s.ID,
+			Time:  s.Time,
+			Code:  s.Code,
+			Text:  file,
+			Image: file + ".png",
+		})
+	}
+	return codes, total
+}
+
+// Code serves the public gallery of the code2img codes of the user of
+// auth.user, newest first. The q query searches the codes, and the
+// page query pages through them.
+func (m *Midgard) Code(c *gin.Context) {
+	if !m.policy.Public("/code") {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var in types.ListCodeInput
+	_ = c.ShouldBindQuery(&in)
+	in.Size = 0 // the page size of the gallery is fixed
+	codes, total := m.searchCodes(m.accounts[config.S().Auth.User], &in)
+
+	type codeInfo struct {
+		TimeFmt  string
+		Code     string
+		ImageURL string
+		TextURL  string
+	}
+	pages := max((total+in.Size-1)/in.Size, 1)
+	page := struct {
+		Query              string
+		Total, Page, Pages int
+		Codes              []codeInfo
+		Newer, Older       string
+	}{Query: in.Query, Total: total, Page: in.Page, Pages: pages}
+	for _, code := range codes {
+		page.Codes = append(page.Codes, codeInfo{
+			TimeFmt:  code.Time.Format(time.RFC1123),
+			Code:     code.Code,
+			ImageURL: code.Image,
+			TextURL:  code.Text,
+		})
+	}
+	link := func(p int) string {
+		q := url.Values{"page": {fmt.Sprint(p)}}
+		if in.Query != "" {
+			q.Set("q", in.Query)
+		}
+		return "?" + q.Encode()
+	}
+	if in.Page > 1 {
+		page.Newer = link(min(in.Page-1, pages))
+	}
+	if in.Page < pages {
+		page.Older = link(in.Page + 1)
+	}
+
+	var buf bytes.Buffer
+	err := codeTmpl.Execute(&buf, page)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusBadRequest)
+		log.Println(err)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// ListCode lists the code2img codes of the authenticated user, newest
+// first.
+func (m *Midgard) ListCode(c *gin.Context) {
+	var in types.ListCodeInput
+	_ = c.ShouldBindQuery(&in)
+	codes, total := m.searchCodes(m.account(c), &in)
+	c.JSON(http.StatusOK, types.ListCodeOutput{
+		Codes:   codes,
+		Total:   total,
+		Page:    in.Page,
+		Size:    in.Size,
+		Message: "success.",
+	})
+}
+
+// DeleteCode deletes a code2img code of the authenticated user and its
+// image.
+func (m *Midgard) DeleteCode(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := time.Parse(code2imgTimeFormat, id); err != nil {
+		c.JSON(http.StatusBadRequest, types.DeleteCodeOutput{
+			Message: fmt.Sprintf("invalid code id: %s", id),
+		})
+		return
+	}
+	codefile := m.account(c).namespace + "/code/" + id
+	if _, err := m.repo.Stat(codefile); err != nil {
+		c.JSON(http.StatusNotFound, types.DeleteCodeOutput{
+			Message: fmt.Sprintf("code %s does not exist.", id),
+		})
+		return
+	}
+	for _, file := range []string{codefile, codefile + ".png"} {
+		if err := m.removeResource(file); err != nil {
+			c.JSON(http.StatusInternalServerError, types.DeleteCodeOutput{
+				Message: fmt.Sprintf("failed to delete code %s: %v", id, err),
+			})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, types.DeleteCodeOutput{
+		Message: fmt.Sprintf("code %s is deleted.", id),
+	})
+}
+
+// forgetCode removes the code of the given path relative to the repo
+// from the gallery of its owner, if the path is a code of code2img.
+func (m *Midgard) forgetCode(rel string) {
+	owner := m.owner(rel)
+	a, ok := m.accounts[owner]
+	if !ok {
+		return
+	}
+	dir, file := path.Split(rel)
+	if dir != a.namespace+"/code/" || path.Ext(file) != "" {
+		return
+	}
+	if x, ok := m.codes[owner]; ok {
+		x.Remove(file)
+	}
+}
@@ -10,18 +10,19 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"html/template"
+	"io"
 	"io/fs"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
 	"changkun.de/x/midgard/internal/clipboard"
 	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/namespace"
+	"changkun.de/x/midgard/internal/resource"
+	"changkun.de/x/midgard/internal/storage"
+	"changkun.de/x/midgard/internal/token"
 	"changkun.de/x/midgard/internal/types"
 	"changkun.de/x/midgard/internal/utils"
 	"changkun.de/x/midgard/internal/version"
@@ -37,102 +38,21 @@ func (m *Midgard) PingPong(c *gin.Context) {
 	})
 }
 
-const codeTmpl = `{{ range .Codes }}
-<pre>--- {{ .TimeFmt }} ---: <a href="{{ .ImageURL }}">Image<a/>, <a href="{{ .TextURL }}">Text<a/></pre>
-<pre>
-{{ .Code }}
-</pre>
-{{ end }}
-`
-
-// Code lists all code2img codes
-func (m *Midgard) Code(c *gin.Context) {
-	type codeInfo struct {
-		Codes []struct {
-			Time     time.Time
-			TimeFmt  string
-			Code     string
-			ImageURL string
-			TextURL  string
-		}
-	}
-	ci := codeInfo{}
-	codedir := filepath.Clean(config.RepoPath + "/code")
-	filepath.WalkDir(codedir, func(path string, d fs.DirEntry, err error) error {
-		if d.IsDir() || err != nil {
-			return nil
-		}
-
-		dir, file := filepath.Split(path)
-		if strings.Compare(dir, codedir+"/") != 0 {
-			return nil
-		}
-		ext := filepath.Ext(file)
-		if ext != "" {
-			return nil
-		}
-
-		b, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-
-		t, err := time.Parse(code2imgTimeFormat, file)
-		if err != nil {
-			return nil // Ignore errors from this file
-		}
-
-		ci.Codes = append(ci.Codes, struct {
-			Time     time.Time
-			TimeFmt  string
-			Code     string
-			ImageURL string
-			TextURL  string
-		}{
-			Time:     t,
-			TimeFmt:  t.Format(time.RFC1123),
-			Code:     utils.BytesToString(b),
-			ImageURL: fmt.Sprintf("code/%s.png", file),
-			TextURL:  fmt.Sprintf("code/%s", file),
-		})
-		return nil
-	})
-
-	sort.Slice(ci.Codes, func(i, j int) bool {
-		return ci.Codes[i].Time.Sub(ci.Codes[j].Time) > 0
-	})
-
-	tt := template.Must(template.New("codes").Parse(codeTmpl))
-	var buf bytes.Buffer
-	err := tt.Execute(&buf, ci)
-	if err != nil {
-		c.Writer.WriteHeader(http.StatusBadRequest)
-		log.Println(err)
-		return
-	}
-	c.Header("Cache-Control", "public, max-age=300")
-	c.Header("Content-Type", "text/html")
-	c.Writer.Write(buf.Bytes())
-}
-
 // GetFromUniversalClipboard returns the in-memory clipboard data inside
 // the midgard server
 func (m *Midgard) GetFromUniversalClipboard(c *gin.Context) {
-	t, buf := clipboard.Universal.Read()
-
-	var raw string
-	if t == types.MIMEImagePNG {
-		// We stored our clipboard in bytes, if client is retriving this
-		// data, then let's encode it into base64.
-		raw = base64.StdEncoding.EncodeToString(buf)
-	} else {
-		raw = utils.BytesToString(buf)
+	var in types.GetFromUniversalClipboardInput
+	_ = c.ShouldBindQuery(&in)
+	cb, err := m.clipboard(m.account(c), in.Channel)
+	if err != nil {
+		c.String(http.StatusForbidden, err.Error())
+		return
 	}
 
-	c.JSON(http.StatusOK, types.GetFromUniversalClipboardOutput{
-		Type: t,
-		Data: raw,
-	})
+	// We stored our clipboard in bytes, if client is retriving
+	// non-text data, then it is encoded into base64.
+	c.JSON(http.StatusOK, types.GetFromUniversalClipboardOutput(
+		types.EncodeClipboardData(cb.ReadAll()...)))
 }
 
 // PutToUniversalClipboard saves data to the in-memory clipboard data
@@ -149,19 +69,30 @@ func (m *Midgard) PutToUniversalClipboard(c *gin.Context) {
 		return
 	}
 
-	var raw []byte
-	if b.Type == types.MIMEImagePNG {
-		// We assume the client send us a base64 encoded image data,
-		// Let's decode it into bytes.
-		raw, err = base64.StdEncoding.DecodeString(b.Data)
-		if err != nil {
-			raw = []byte{}
-		}
-	} else {
-		raw = utils.StringToBytes(b.Data)
+	// We assume the client send us base64 encoded data if it is not
+	// plain text. Unknown MIME types are stored as opaque payloads.
+	reps, err := b.Decode()
+	if err != nil {
+		err = fmt.Errorf("cannot decode clipboard data, err: %w", err)
+		c.JSON(http.StatusBadRequest, types.PutToUniversalClipboardOutput{
+			Message: err.Error(),
+		})
+		return
 	}
 
-	updated := clipboard.Universal.Write(b.Type, raw)
+	if b.DaemonID == "" {
+		b.DaemonID = c.ClientIP()
+	}
+
+	a := m.account(c)
+	cb, err := m.clipboard(a, b.Channel)
+	if err != nil {
+		c.JSON(http.StatusForbidden, types.PutToUniversalClipboardOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	updated := cb.Put(b.DaemonID, reps...)
 	c.JSON(http.StatusOK, types.PutToUniversalClipboardOutput{
 		Message: "clipboard data is saved.",
 	})
@@ -169,49 +100,187 @@ func (m *Midgard) PutToUniversalClipboard(c *gin.Context) {
 		return
 	}
 
-	if b.DaemonID == "" {
-		b.DaemonID = c.ClientIP()
-	}
-
 	// Include MIME type information so that the clipboard is
 	// consistent after sync propagation.
-	raw, _ = json.Marshal(b.ClipboardData)
-	m.boardcastMessage(&types.WebsocketMessage{
+	raw, _ := json.Marshal(b.ClipboardData)
+	m.boardcastMessage(a, &types.WebsocketMessage{
 		Action:  types.ActionClipboardChanged,
 		UserID:  b.DaemonID,
+		Channel: b.Channel,
 		Message: "universal clipboard has changes",
 		Data:    raw,
 	})
 }
 
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// GetClipboardHistory returns the change history of the universal
+// clipboard, newest first. The result can be paginated using offset
+// and limit, and filtered by a time range using since and until.
+func (m *Midgard) GetClipboardHistory(c *gin.Context) {
+	var in types.GetClipboardHistoryInput
+	err := c.ShouldBindQuery(&in)
+	if err != nil {
+		err = fmt.Errorf("cannot bind requested query, err: %w", err)
+		c.JSON(http.StatusBadRequest, types.GetClipboardHistoryOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	if in.Offset < 0 || in.Limit < 0 {
+		c.JSON(http.StatusBadRequest, types.GetClipboardHistoryOutput{
+			Message: "offset and limit must not be negative.",
+		})
+		return
+	}
+	if in.Limit == 0 {
+		in.Limit = defaultHistoryLimit
+	}
+	if in.Limit > maxHistoryLimit {
+		in.Limit = maxHistoryLimit
+	}
+
+	cb, err := m.clipboard(m.account(c), in.Channel)
+	if err != nil {
+		c.JSON(http.StatusForbidden, types.GetClipboardHistoryOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var (
+		history = cb.History()
+		entries []clipboard.HistoryEntry
+		total   int
+	)
+	if in.ID != 0 {
+		e, ok := history.Get(in.ID)
+		if !ok {
+			c.JSON(http.StatusNotFound, types.GetClipboardHistoryOutput{
+				Message: fmt.Sprintf("history entry %d does not exist.", in.ID),
+			})
+			return
+		}
+		entries, total = []clipboard.HistoryEntry{e}, 1
+	} else {
+		entries, total = history.List(clipboard.HistoryQuery{
+			Offset: in.Offset,
+			Limit:  in.Limit,
+			Since:  in.Since,
+			Until:  in.Until,
+		})
+	}
+
+	out := types.GetClipboardHistoryOutput{
+		Total:   total,
+		Entries: make([]types.ClipboardHistoryEntry, 0, len(entries)),
+		Message: "success.",
+	}
+	for _, e := range entries {
+		out.Entries = append(out.Entries, types.ClipboardHistoryEntry{
+			ClipboardData: types.EncodeClipboardData(e.Representations()...),
+			ID:            e.ID,
+			Time:          e.Time,
+			DaemonID:      e.Source,
+			Hash:          e.Hash,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// RestoreClipboardHistory puts an earlier entry of the clipboard history
+// back to the universal clipboard, and broadcasts the change to all daemons.
+func (m *Midgard) RestoreClipboardHistory(c *gin.Context) {
+	var in types.RestoreClipboardHistoryInput
+	err := c.ShouldBindJSON(&in)
+	if err != nil {
+		err = fmt.Errorf("cannot bind requested data, err: %w", err)
+		c.JSON(http.StatusBadRequest, types.RestoreClipboardHistoryOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	a := m.account(c)
+	cb, err := m.clipboard(a, in.Channel)
+	if err != nil {
+		c.JSON(http.StatusForbidden, types.RestoreClipboardHistoryOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	e, ok := cb.History().Get(in.ID)
+	if !ok {
+		c.JSON(http.StatusNotFound, types.RestoreClipboardHistoryOutput{
+			Message: fmt.Sprintf("history entry %d does not exist.", in.ID),
+		})
+		return
+	}
+
+	if in.DaemonID == "" {
+		in.DaemonID = c.ClientIP()
+	}
+	updated := cb.Put(in.DaemonID, e.Representations()...)
+	c.JSON(http.StatusOK, types.RestoreClipboardHistoryOutput{
+		Message: fmt.Sprintf("history entry %d is restored.", e.ID),
+	})
+	if !updated {
+		return
+	}
+
+	b, _ := json.Marshal(types.EncodeClipboardData(e.Representations()...))
+
+	// The requesting daemon also needs the restored data, hence the
+	// message is not attributed to any daemon.
+	m.boardcastMessage(a, &types.WebsocketMessage{
+		Action:  types.ActionClipboardChanged,
+		Channel: in.Channel,
+		Message: "universal clipboard is restored from history",
+		Data:    b,
+	})
+}
+
 // AllocateURL generates an universal access URL for the requested resource.
 // The requested resource can be an attached data, the midgard universal
 // clipboard, and etc.
 func (m *Midgard) AllocateURL(c *gin.Context) {
 	var in types.AllocateURLInput
+	m.limitBody(c)
 	err := c.ShouldBindJSON(&in)
 	if err != nil {
 		err = fmt.Errorf("cannot bind requested data, err: %w", err)
-		c.JSON(http.StatusBadRequest, types.AllocateURLOutput{
+		c.JSON(bindStatus(err), types.AllocateURLOutput{
 			Message: err.Error(),
 		})
 		return
 	}
 
 	// check request source, determine resource type.
-	// if the type cannot be determined, then mark it as plain text.
+	// if the type is not given, then detect it from the data.
+	a := m.account(c)
 	var (
-		ext  = ".txt"
+		typ  types.MIME
 		data []byte
 	)
 	switch in.Source {
 	case types.SourceUniversalClipboard:
-		t, raw := clipboard.Universal.Read()
-		data = raw
-		if t == types.MIMEImagePNG {
-			ext = ".png"
+		if !requireScope(c, token.ScopeClipboardRead) {
+			return
 		}
+		t, raw := a.clipboard.Read()
+		if t == types.MIMEEncrypted {
+			c.JSON(http.StatusBadRequest, types.AllocateURLOutput{
+				Message: "universal clipboard is end-to-end encrypted, upload your data as an attachment.",
+			})
+			return
+		}
+		data = raw
+		typ = t
 	case types.SourceAttachment:
+		typ = in.Type
 		data, err = base64.StdEncoding.DecodeString(in.Data)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, types.AllocateURLOutput{
@@ -228,7 +297,69 @@ func (m *Midgard) AllocateURL(c *gin.Context) {
 		return
 	}
 
-	root := config.RepoPath
+	if typ == "" {
+		typ = types.DetectMIME(data)
+	}
+
+	if err := m.quota.Reserve(a.name, int64(len(data))); err != nil {
+		c.JSON(quotaStatus(err), types.AllocateURLOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	url, code, err := m.allocate(a, &in, typ, int64(len(data)), func(key string) error {
+		return m.repo.Put(key, bytes.NewReader(data))
+	})
+	if err != nil {
+		c.JSON(code, types.AllocateURLOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, types.AllocateURLOutput{
+		URL:     url,
+		Message: "success.",
+	})
+}
+
+// allocate allocates a path for the requested resource in the namespace
+// of the given account, and calls save to save the resource to the key
+// of the path in the repo. The size of the resource must be reserved in
+// the quota of the account, it is released if the allocation fails.
+// The resource is served as the given type, and a random path gets the
+// extension of the type. It returns the URL of the resource, or an HTTP
+// status code and an error.
+func (m *Midgard) allocate(a *account, in *types.AllocateURLInput, typ types.MIME, size int64, save func(key string) error) (url string, code int, err error) {
+	reserved := true
+	defer func() {
+		if err != nil && reserved {
+			m.quota.Release(a.name, size)
+		}
+	}()
+
+	meta := resource.Meta{
+		Created:  time.Now().UTC(),
+		Type:     typ.ContentType(),
+		MaxViews: in.MaxViews,
+	}
+	if in.TTL != "" {
+		ttl, err := time.ParseDuration(in.TTL)
+		if err != nil || ttl <= 0 {
+			return "", http.StatusBadRequest, fmt.Errorf("invalid ttl: %v", in.TTL)
+		}
+		meta.Expires = meta.Created.Add(ttl)
+	}
+	if in.MaxViews < 0 {
+		return "", http.StatusBadRequest, errors.New("max views must not be negative.")
+	}
+	if in.Password != "" {
+		meta.Password, err = resource.HashPassword(in.Password)
+		if err != nil {
+			return "", http.StatusInternalServerError, fmt.Errorf("failed to protect the resource: %v", err)
+		}
+	}
+
+	// resources of additional users are allocated in their own namespace.
 	var path string
 
 	id, err := utils.NewUUIDShort()
@@ -238,48 +369,82 @@ func (m *Midgard) AllocateURL(c *gin.Context) {
 
 	// if URI is empty, then generate a random path
 	if in.URI == "" {
-		path = root + "/random/" + id + ext
+		path = a.namespace + "/random/" + id + typ.Ext()
 	} else {
-		path = root + "/" + strings.TrimPrefix(in.URI, "/")
+		path, err = namespace.Allocate(a.namespace, in.URI)
+		if err != nil {
+			return "", http.StatusBadRequest, err
+		}
 	}
 
 	// check if the path is availiable, if not then throw an error
-	existed := func(path string) bool {
-		_, err := os.Stat(path)
-		return !errors.Is(err, os.ErrNotExist)
+	if _, err := m.repo.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		return "", http.StatusBadRequest, errors.New("the requested uri already existed.")
 	}
 
-	if existed(path) {
-		c.JSON(http.StatusBadRequest, types.AllocateURLOutput{
-			Message: "the requested uri already existed.",
-		})
-		return
+	// everything seems fine, save the data
+	err = save(path)
+	if err != nil {
+		return "", http.StatusInternalServerError, fmt.Errorf("failed to persist the data, err: %w", err)
 	}
 
-	dir, _ := filepath.Split(path)
-	if !existed(dir) {
-		err = os.MkdirAll(dir, fs.ModeDir|fs.ModePerm)
-		if err != nil {
-			err = fmt.Errorf("failed to create uri, err: %w", err)
-			c.JSON(http.StatusInternalServerError, types.AllocateURLOutput{
-				Message: err.Error(),
-			})
-			return
+	// identical content shares storage, and an identical public resource
+	// keeps its random path. Only local storages are deduplicated.
+	meta.Path = path
+	if l, ok := m.repo.(storage.Local); ok {
+		if blob, err := m.blobs.Add(l.Path(path)); err != nil {
+			log.Printf("failed to deduplicate %s: %v", meta.Path, err)
+		} else {
+			meta.Blob = blob
+		}
+	}
+	if in.URI == "" && meta.Blob != "" && !meta.Ephemeral() && !meta.Protected() {
+		if p, ok := m.duplicate(a, meta.Blob); ok {
+			if err := m.repo.Remove(path); err != nil {
+				log.Printf("failed to remove duplicate %s: %v", meta.Path, err)
+			} else {
+				m.quota.Release(a.name, size)
+			}
+			return config.S().Store.Prefix + p, http.StatusOK, nil
 		}
 	}
 
-	// everything seems fine, save the data
-	err = os.WriteFile(path, data, fs.ModePerm)
+	// ephemeral resources are tracked for removal, the metadata also
+	// excludes resources from the backup, see backedUp.
+	err = m.resources.Put(meta)
 	if err != nil {
-		err = fmt.Errorf("failed to persist the data, err: %w", err)
-		c.JSON(http.StatusInternalServerError, types.AllocateURLOutput{
-			Message: err.Error(),
-		})
-		return
+		m.logRemoveResource(meta.Path) // releases the quota
+		reserved = false
+		// the blob is not referred by the metadata, hence not released
+		// by the removal of the resource.
+		if err := m.releaseBlob(meta.Blob); err != nil {
+			log.Printf("failed to remove blob of %s: %v", meta.Path, err)
+		}
+		return "", http.StatusInternalServerError, fmt.Errorf("failed to persist the metadata, err: %w", err)
 	}
+	return config.S().Store.Prefix + meta.Path, http.StatusOK, nil
+}
 
-	c.JSON(http.StatusOK, types.AllocateURLOutput{
-		URL:     config.S().Store.Prefix + strings.TrimPrefix(path, root),
-		Message: "success.",
-	})
+// duplicate returns the path of a public resource of the given blob that
+// is allocated at a random path of the given account.
+func (m *Midgard) duplicate(a *account, blob string) (string, bool) {
+	for _, r := range m.resources.References(blob) {
+		if !strings.HasPrefix(r.Path, a.namespace+"/random/") || r.Ephemeral() || r.Protected() {
+			continue
+		}
+		if _, err := m.repo.Stat(r.Path); err == nil {
+			return r.Path, true
+		}
+	}
+	return "", false
+}
+
+// read reads the whole object of the given key in the repo.
+func (m *Midgard) read(key string) ([]byte, error) {
+	r, _, err := m.repo.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
 }
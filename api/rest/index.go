@@ -0,0 +1,239 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/storage"
+	"changkun.de/x/midgard/internal/thumbnail"
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	indexPath     = "/midgard/api/v1/index"
+	thumbnailSize = 160      // pixels
+	maxThumbnail  = 32 << 20 // bytes of an image that gets a thumbnail
+	thumbnailLRU  = 32 << 20 // bytes of cached thumbnails
+
+	indexTimeFormat = "2006-01-02 15:04"
+)
+
+var indexTmpl = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Index of {{ .Path }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 4px 12px; text-align: left; vertical-align: middle; }
+tr:nth-child(even) { background: #f5f5f5; }
+td.thumb { width: 80px; height: 80px; text-align: center; }
+td.thumb img { max-width: 80px; max-height: 80px; }
+td.size { text-align: right; }
+.notes { color: #888; }
+</style>
+</head>
+<body>
+<h1>Index of {{ .Path }}</h1>
+<table>
+<tr><th></th><th>Name</th><th>Size</th><th>Modified</th><th></th></tr>
+{{ if .Parent }}<tr><td class="thumb"></td><td><a href="{{ .Parent }}">../</a></td><td></td><td></td><td></td></tr>
+{{ end }}{{ range .Entries }}<tr>
+<td class="thumb">{{ if .Thumbnail }}<a href="{{ .URL }}"><img src="{{ .Thumbnail }}" alt="" loading="lazy"></a>{{ end }}</td>
+<td><a href="{{ .URL }}">{{ .Name }}</a></td>
+<td class="size">{{ .Size }}</td>
+<td>{{ .Modified }}</td>
+<td class="notes">{{ .Notes }}</td>
+</tr>
+{{ end }}</table>
+</body>
+</html>
+`))
+
+// indexEntry is an entry of an index page.
+type indexEntry struct {
+	Name      string
+	URL       string
+	Thumbnail string
+	Size      string
+	Modified  string
+	Notes     string
+}
+
+// Index serves the index page of a folder in the namespace of the
+// authenticated user, or the list of its resources as JSON if the
+// format=json query is given. Images link a thumbnail at the thumb
+// query, which does not count as a view of the resource.
+func (m *Midgard) Index(c *gin.Context) {
+	a := m.account(c)
+	p := c.Param("path")
+	rel, err := a.resolve(p)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	info, err := m.repo.Stat(rel)
+	if err != nil || isInternal(rel) {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if !info.Dir {
+		if _, ok := c.GetQuery("thumb"); ok {
+			m.serveThumbnail(c, rel, info)
+			return
+		}
+		u := url.URL{Path: config.S().Store.Prefix + rel}
+		c.Redirect(http.StatusFound, u.String())
+		return
+	}
+	if !strings.HasSuffix(p, "/") {
+		c.Redirect(http.StatusMovedPermanently, path.Base(p)+"/")
+		return
+	}
+
+	infos, err := m.repo.List(rel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.ListResourcesOutput{
+			Message: fmt.Sprintf("failed to list %s: %v", p, err),
+		})
+		return
+	}
+	rs := m.resourceInfos(a, infos)
+	sort.Slice(rs, func(i, j int) bool {
+		if rs[i].Dir != rs[j].Dir {
+			return rs[i].Dir
+		}
+		return rs[i].Path < rs[j].Path
+	})
+	for i := range rs {
+		if !rs[i].Dir && hasThumbnail(rs[i]) {
+			rs[i].Thumbnail = indexPath + rs[i].Path + "?thumb"
+		}
+	}
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, types.ListResourcesOutput{
+			Resources: rs,
+			Message:   "success.",
+		})
+		return
+	}
+
+	dir := a.unresolve(rel)
+	page := struct {
+		Path    string
+		Parent  string
+		Entries []indexEntry
+	}{Path: strings.TrimSuffix(dir, "/") + "/"}
+	if dir != "/" {
+		page.Parent = indexPath + strings.TrimSuffix(path.Dir(dir), "/") + "/"
+	}
+	for _, r := range rs {
+		e := indexEntry{
+			Name:      path.Base(r.Path),
+			URL:       r.URL,
+			Thumbnail: r.Thumbnail,
+			Size:      utils.ByteSize(r.Size),
+			Modified:  r.ModTime.Local().Format(indexTimeFormat),
+			Notes:     resourceNotes(r),
+		}
+		if r.Dir {
+			e.Name += "/"
+			e.URL = indexPath + r.Path + "/"
+			e.Size = "-"
+		}
+		page.Entries = append(page.Entries, e)
+	}
+
+	var buf bytes.Buffer
+	err = indexTmpl.Execute(&buf, page)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+}
+
+// hasThumbnail reports whether the resource is an image that gets a
+// thumbnail.
+func hasThumbnail(r types.ResourceInfo) bool {
+	typ := r.Type
+	if typ == "" {
+		typ = mime.TypeByExtension(path.Ext(r.Path))
+	}
+	switch strings.SplitN(typ, ";", 2)[0] {
+	case "image/png", "image/jpeg", "image/gif":
+		return r.Size <= maxThumbnail
+	}
+	return false
+}
+
+// resourceNotes describes the limits of a resource.
+func resourceNotes(r types.ResourceInfo) string {
+	var notes []string
+	if !r.Expires.IsZero() {
+		notes = append(notes, "expires "+r.Expires.Local().Format(indexTimeFormat))
+	}
+	if r.MaxViews > 0 {
+		notes = append(notes, fmt.Sprintf("%d/%d views", r.Views, r.MaxViews))
+	}
+	if r.Protected {
+		notes = append(notes, "protected")
+	}
+	return strings.Join(notes, ", ")
+}
+
+// serveThumbnail serves the thumbnail of the image of the given path.
+// Thumbnails are cached by the blob of the image, or by its path and
+// modification time if it has no blob.
+func (m *Midgard) serveThumbnail(c *gin.Context, rel string, info storage.Info) {
+	if info.Size > maxThumbnail {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	key := fmt.Sprintf("%s@%d", rel, info.ModTime.UnixNano())
+	if meta, ok := m.resources.Get(rel); ok && meta.Blob != "" {
+		key = meta.Blob
+	}
+	b, ok := m.thumbnails.Get(key)
+	if !ok {
+		r, _, err := m.repo.Open(rel)
+		if err != nil {
+			c.Writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		defer r.Close()
+		b, err = thumbnail.Render(r, thumbnailSize)
+		if errors.Is(err, thumbnail.ErrUnsupported) {
+			c.Writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("failed to render thumbnail of %s: %v", rel, err)
+			c.Writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		m.thumbnails.Add(key, b)
+	}
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, "image/png", b)
+}
@@ -0,0 +1,222 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/namespace"
+	"changkun.de/x/midgard/internal/storage"
+	"changkun.de/x/midgard/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// resolve resolves the given path in the namespace of the account to a
+// path relative to the repo. Internal files cannot be resolved.
+func (a *account) resolve(p string) (string, error) {
+	return namespace.Resolve(a.namespace, p)
+}
+
+// unresolve is the reverse of resolve.
+func (a *account) unresolve(rel string) string {
+	p := strings.TrimPrefix(rel, a.namespace)
+	if p == "" {
+		return "/"
+	}
+	return p
+}
+
+// isInternal reports whether the given path relative to the repo
+// belongs to the internal files of midgard or the backup.
+func isInternal(rel string) bool {
+	for _, dir := range []string{metaDir, "/.git"} {
+		if rel == dir || strings.HasPrefix(rel, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ListResources lists the resources in a folder of the namespace of the
+// authenticated user.
+func (m *Midgard) ListResources(c *gin.Context) {
+	var in types.ListResourcesInput
+	_ = c.ShouldBindQuery(&in)
+
+	a := m.account(c)
+	dir, err := a.resolve(in.Path)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.ListResourcesOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	infos, err := m.repo.List(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.JSON(http.StatusNotFound, types.ListResourcesOutput{
+			Message: fmt.Sprintf("%s does not exist.", in.Path),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.ListResourcesOutput{
+			Message: fmt.Sprintf("failed to list %s: %v", in.Path, err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.ListResourcesOutput{
+		Resources: m.resourceInfos(a, infos),
+		Message:   "success.",
+	})
+}
+
+// resourceInfos describes the listed objects of the repo in the
+// namespace of the account, internal files and files whose names start
+// with a dot are left out.
+func (m *Midgard) resourceInfos(a *account, infos []storage.Info) []types.ResourceInfo {
+	rs := []types.ResourceInfo{}
+	for _, info := range infos {
+		rel := info.Key
+		if isInternal(rel) || strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		r := types.ResourceInfo{
+			Path:    a.unresolve(rel),
+			URL:     config.S().Store.Prefix + rel,
+			Dir:     info.Dir,
+			Size:    info.Size,
+			ModTime: info.ModTime,
+		}
+		if meta, ok := m.resources.Get(rel); ok {
+			r.Type = meta.Type
+			r.Expires = meta.Expires
+			r.MaxViews = meta.MaxViews
+			r.Views = meta.Views
+			r.Protected = meta.Protected()
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+// DeleteResource deletes a resource or an empty folder in the namespace
+// of the authenticated user.
+func (m *Midgard) DeleteResource(c *gin.Context) {
+	var in types.DeleteResourceInput
+	_ = c.ShouldBindQuery(&in)
+
+	a := m.account(c)
+	rel, err := a.resolve(in.Path)
+	if err == nil && rel == path.Clean("/"+a.namespace) {
+		err = errors.New("cannot delete the root folder")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.DeleteResourceOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	info, err := m.repo.Stat(rel)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.JSON(http.StatusNotFound, types.DeleteResourceOutput{
+			Message: fmt.Sprintf("%s does not exist.", in.Path),
+		})
+		return
+	}
+	if err == nil {
+		if info.Dir {
+			err = m.repo.Remove(rel)
+		} else {
+			err = m.removeResource(rel)
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.DeleteResourceOutput{
+			Message: fmt.Sprintf("failed to delete %s: %v", in.Path, err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, types.DeleteResourceOutput{
+		Message: fmt.Sprintf("%s is deleted.", in.Path),
+	})
+}
+
+// MoveResource moves a resource to another path in the namespace of the
+// authenticated user, the metadata of the resource moves along.
+func (m *Midgard) MoveResource(c *gin.Context) {
+	var in types.MoveResourceInput
+	err := c.ShouldBindJSON(&in)
+	if err != nil {
+		err = fmt.Errorf("cannot bind requested data, err: %w", err)
+		c.JSON(http.StatusBadRequest, types.MoveResourceOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	a := m.account(c)
+	from, err := a.resolve(in.From)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.MoveResourceOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	to, err := namespace.Allocate(a.namespace, in.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.MoveResourceOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	info, err := m.repo.Stat(from)
+	if err != nil || info.Dir {
+		c.JSON(http.StatusNotFound, types.MoveResourceOutput{
+			Message: fmt.Sprintf("%s is not an existing resource.", in.From),
+		})
+		return
+	}
+	if _, err := m.repo.Stat(to); !errors.Is(err, fs.ErrNotExist) {
+		c.JSON(http.StatusBadRequest, types.MoveResourceOutput{
+			Message: fmt.Sprintf("%s already existed.", in.To),
+		})
+		return
+	}
+
+	err = m.moveResource(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.MoveResourceOutput{
+			Message: fmt.Sprintf("failed to move %s: %v", in.From, err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, types.MoveResourceOutput{
+		URL:     config.S().Store.Prefix + to,
+		Message: fmt.Sprintf("%s is moved to %s.", in.From, in.To),
+	})
+}
+
+// moveResource moves the resource of the given path relative to the
+// repo, and its metadata.
+func (m *Midgard) moveResource(from, to string) error {
+	err := m.repo.Rename(from, to)
+	if err != nil {
+		return err
+	}
+	m.forgetCode(from)
+	if _, ok := m.resources.Get(from); !ok {
+		return nil
+	}
+	return m.resources.Move(from, to)
+}
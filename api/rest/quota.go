@@ -0,0 +1,127 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/quota"
+	"changkun.de/x/midgard/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// newQuota creates the configured quota of the repo.
+func (m *Midgard) newQuota() *quota.Quota {
+	conf := config.S().Quota
+	return quota.New(quota.Limits{
+		MaxUpload: conf.MaxUpload,
+		Total:     quota.Usage{Bytes: conf.Total.Bytes, Files: conf.Total.Files},
+		User: func(name string) quota.Usage {
+			l := config.S().UserQuota(name)
+			return quota.Usage{Bytes: l.Bytes, Files: l.Files}
+		},
+	}, m.scanUsage)
+}
+
+// owner returns the name of the user that owns the given path relative
+// to the repo.
+func (m *Midgard) owner(rel string) string {
+	if strings.HasPrefix(rel, "/~") {
+		name := strings.SplitN(strings.TrimPrefix(rel, "/~"), "/", 2)[0]
+		if a, ok := m.accounts[name]; ok {
+			return a.name
+		}
+	}
+	return config.S().Auth.User
+}
+
+// scanUsage counts the storage usage of each user in the repo, and the
+// reserved usage of incomplete uploads.
+func (m *Midgard) scanUsage() (map[string]quota.Usage, error) {
+	usage := map[string]quota.Usage{}
+	for _, ss := range m.uploads.Sessions() {
+		u := usage[ss.User]
+		u.Bytes += ss.Size
+		u.Files++
+		usage[ss.User] = u
+	}
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		infos, err := m.repo.List(dir)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			if isInternal(info.Key) {
+				continue
+			}
+			if info.Dir {
+				if err := walk(info.Key); err != nil {
+					return err
+				}
+				continue
+			}
+			owner := m.owner(info.Key)
+			u := usage[owner]
+			u.Bytes += info.Size
+			u.Files++
+			usage[owner] = u
+		}
+		return nil
+	}
+	if _, err := m.repo.Stat("/"); err != nil {
+		return usage, nil // nothing is allocated yet
+	}
+	return usage, walk("/")
+}
+
+// quotaStatus returns the HTTP status code of a quota error.
+func quotaStatus(err error) int {
+	switch {
+	case errors.Is(err, quota.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, quota.ErrExceeded):
+		return http.StatusInsufficientStorage
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// limitBody limits the request body to the maximum upload size, the
+// data of uploads are base64 encoded in JSON requests.
+func (m *Midgard) limitBody(c *gin.Context) {
+	if max := m.quota.MaxUpload(); max > 0 {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max/3*4+64<<10)
+	}
+}
+
+// bindStatus returns the HTTP status code of an error of binding the
+// request body.
+func bindStatus(err error) int {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// Quota returns the storage usage and limits of the authenticated user.
+func (m *Midgard) Quota(c *gin.Context) {
+	a := m.account(c)
+	usage, limit := m.quota.Usage(a.name)
+	total, totalLimit := m.quota.Total()
+	c.JSON(http.StatusOK, types.QuotaOutput{
+		User:       a.name,
+		Usage:      types.QuotaUsage{Bytes: usage.Bytes, Files: usage.Files},
+		Limit:      types.QuotaUsage{Bytes: limit.Bytes, Files: limit.Files},
+		Total:      types.QuotaUsage{Bytes: total.Bytes, Files: total.Files},
+		TotalLimit: types.QuotaUsage{Bytes: totalLimit.Bytes, Files: totalLimit.Files},
+		MaxUpload:  m.quota.MaxUpload(),
+		Message:    "success.",
+	})
+}
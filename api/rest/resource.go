@@ -0,0 +1,149 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"changkun.de/x/midgard/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// metaDir is the folder of midgard's internal files inside the repo, it
+// is never served.
+const metaDir = "/.midgard"
+
+// sweep removes expired resources and stale uploads periodically
+// until the context is canceled.
+func (m *Midgard) sweep(ctx context.Context) {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			for _, p := range m.resources.Expired(now.UTC()) {
+				log.Printf("remove expired resource: %s", p)
+				m.logRemoveResource(p)
+			}
+			// the quota of an upload is reserved until it completes.
+			for _, ss := range m.uploads.GC(now.Add(-uploadTTL)) {
+				m.quota.Release(ss.User, ss.Size)
+			}
+		}
+	}
+}
+
+// removeResource removes the resource of the given path relative to the
+// repo, its metadata, and its blob if no other resource shares it.
+func (m *Midgard) removeResource(p string) error {
+	info, err := m.repo.Stat(p)
+	if err == nil {
+		err = m.repo.Remove(p)
+		if err == nil {
+			m.quota.Release(m.owner(p), info.Size)
+			m.forgetCode(p)
+		}
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove resource %s: %w", p, err)
+	}
+	meta, _ := m.resources.Get(p)
+	err = m.resources.Delete(p)
+	if err != nil {
+		return fmt.Errorf("failed to remove metadata of %s: %w", p, err)
+	}
+	err = m.releaseBlob(meta.Blob)
+	if err != nil {
+		return fmt.Errorf("failed to remove blob of %s: %w", p, err)
+	}
+	return nil
+}
+
+// releaseBlob removes the blob of the given hash if no resource refers
+// to it any more.
+func (m *Midgard) releaseBlob(blob string) error {
+	if blob == "" || len(m.resources.References(blob)) != 0 {
+		return nil
+	}
+	return m.blobs.Remove(blob)
+}
+
+// logRemoveResource is like removeResource but only logs the error.
+func (m *Midgard) logRemoveResource(p string) {
+	if err := m.removeResource(p); err != nil {
+		log.Println(err)
+	}
+}
+
+// setContentHeaders sets the Content-Type and Content-Disposition of
+// the resource of the given path. The content type is taken from the
+// metadata of the resource, or guessed from its extension otherwise.
+// Resources that browsers cannot display, or that are active documents
+// such as HTML, are downloaded with their file name. Browsers must not
+// sniff the content, which could turn a resource into a document.
+func (m *Midgard) setContentHeaders(c *gin.Context, p string) {
+	ctype := mime.TypeByExtension(path.Ext(p))
+	if meta, ok := m.resources.Get(p); ok && meta.Type != "" {
+		ctype = meta.Type
+	}
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	c.Header("Content-Type", ctype)
+	c.Header("X-Content-Type-Options", "nosniff")
+
+	disposition := "attachment"
+	if t, _, err := mime.ParseMediaType(ctype); err == nil && types.MIME(t).Inline() {
+		disposition = "inline"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{
+		"filename": path.Base(p),
+	}))
+}
+
+// authorizeResource checks the password of the requested resource if
+// it is protected, and challenges the visitor otherwise. Visitors that
+// fail too often are blocked like failed logins. A resource that lost
+// its metadata in a guarded folder may have been protected, it is
+// never served.
+func (m *Midgard) authorizeResource(c *gin.Context, p string) bool {
+	meta, ok := m.resources.Get(p)
+	if !ok && m.resources.Guarded(p) {
+		log.Printf("refuse to serve %s: metadata is missing", p)
+		c.AbortWithStatus(http.StatusForbidden)
+		return false
+	}
+	if !ok || !meta.Protected() {
+		return true
+	}
+
+	ip := c.ClientIP()
+	if _, blocked := m.blocked.Blocked(ip, time.Now().UTC()); blocked {
+		c.AbortWithStatus(http.StatusForbidden)
+		return false
+	}
+	_, pass, ok := c.Request.BasicAuth()
+	if ok && meta.CheckPassword(pass) {
+		c.Header("Cache-Control", "private, no-cache")
+		return true
+	}
+	if ok {
+		m.blocked.Fail(ip, time.Now().UTC())
+	}
+	c.Header("WWW-Authenticate", "Basic realm="+strconv.Quote("Password Protected"))
+	c.AbortWithStatus(http.StatusUnauthorized)
+	return false
+}
@@ -12,8 +12,11 @@ import (
 	"path"
 	"runtime"
 	"strings"
+	"time"
 
 	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/namespace"
+	"changkun.de/x/midgard/internal/token"
 	"github.com/gin-gonic/gin"
 )
 
@@ -21,42 +24,128 @@ func (m *Midgard) routers() (r *gin.Engine) {
 	gin.SetMode(config.S().Mode)
 
 	r = gin.Default()
-	r.NoRoute(staticHandler(config.S().Store.Prefix, config.RepoPath))
+	r.NoRoute(m.staticHandler(config.S().Store.Prefix))
 
 	mg := r.Group("/midgard")
 	mg.GET("/ping", m.PingPong)
 	mg.GET("/code", m.Code)
 
-	v1auth := mg.Group("/api/v1", BasicAuthWithAttemptsControl(Credentials{
-		config.S().Auth.User: config.S().Auth.Pass,
-	}))
+	var (
+		read  = requireScopes(token.ScopeClipboardRead)
+		write = requireScopes(token.ScopeClipboardWrite)
+		sync  = requireScopes(token.ScopeClipboardRead, token.ScopeClipboardWrite)
+		alloc = requireScopes(token.ScopeAllocate)
+		c2img = requireScopes(token.ScopeCode2img)
+		passw = requireScopes()
+	)
+	v1auth := mg.Group("/api/v1", BasicAuthWithAttemptsControl(credentials(), m.tokens, m.blocked))
 	{
-		v1auth.GET("/clipboard", m.GetFromUniversalClipboard)
-		v1auth.POST("/clipboard", m.PutToUniversalClipboard)
-		v1auth.GET("/ws", m.Subscribe)
-		v1auth.PUT("/allocate", m.AllocateURL)
-		v1auth.POST("/code2img", m.Code2img)
+		v1auth.GET("/clipboard", read, m.GetFromUniversalClipboard)
+		v1auth.POST("/clipboard", write, m.PutToUniversalClipboard)
+		v1auth.GET("/clipboard/history", read, m.GetClipboardHistory)
+		v1auth.POST("/clipboard/restore", write, m.RestoreClipboardHistory)
+		v1auth.GET("/ws", sync, m.Subscribe)
+		v1auth.PUT("/allocate", alloc, m.AllocateURL)
+		v1auth.POST("/uploads", alloc, m.CreateUpload)
+		v1auth.GET("/uploads/:id", alloc, m.GetUpload)
+		v1auth.PATCH("/uploads/:id", alloc, m.AppendUpload)
+		v1auth.GET("/resources", alloc, m.ListResources)
+		v1auth.DELETE("/resources", alloc, m.DeleteResource)
+		v1auth.POST("/resources/move", alloc, m.MoveResource)
+		v1auth.GET("/quota", alloc, m.Quota)
+		v1auth.POST("/code2img", c2img, m.Code2img)
+		v1auth.GET("/code", c2img, m.ListCode)
+		v1auth.DELETE("/code/:id", c2img, m.DeleteCode)
+		v1auth.GET("/status", passw, m.Status)
+		v1auth.GET("/tokens", passw, m.ListTokens)
+		v1auth.POST("/tokens", passw, m.CreateToken)
+		v1auth.DELETE("/tokens/:id", passw, m.RevokeToken)
+		v1auth.GET("/blocklist", passw, m.requireAdmin, m.ListBlocklist)
+		v1auth.DELETE("/blocklist", passw, m.requireAdmin, m.ClearBlocklist)
+		if config.S().Store.Index {
+			v1auth.GET("/index/*path", alloc, m.Index)
+		}
 	}
 
 	profile(mg.Group("/api/v1"))
 	return
 }
 
-func staticHandler(prefix, root string) gin.HandlerFunc {
-	fs := gin.Dir(root, false)
-	fileServer := http.StripPrefix(prefix, http.FileServer(fs))
-
+// staticHandler serves the allocated resources by the serving policy.
+// Internal files of midgard are never served, and the view limits of
+// resources are enforced, a resource is removed after its last view.
+func (m *Midgard) staticHandler(prefix string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		file := strings.TrimPrefix(c.Request.URL.String(), prefix)
+		p := c.Request.URL.Path
+		if p != prefix && !strings.HasPrefix(p, strings.TrimSuffix(prefix, "/")+"/") {
+			c.Writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		file, err := namespace.Clean(strings.TrimPrefix(p, prefix))
+		if err != nil || !m.policy.Public(file) {
+			c.Writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+
 		// Check if file exists and/or if we have permission to access it
-		f, err := fs.Open(file)
+		info, err := m.repo.Stat(file)
 		if err != nil {
 			c.Writer.WriteHeader(http.StatusNotFound)
 			return
 		}
-		f.Close()
-		fileServer.ServeHTTP(c.Writer, c.Request)
+		if info.Dir {
+			m.serveDir(c)
+			return
+		}
+
+		// protected resources challenge visitors for the password, the
+		// user name is ignored.
+		if !m.authorizeResource(c, file) {
+			return
+		}
+
+		// only downloads count as views, e.g. HEAD requests do not.
+		now, allowed, last := time.Now().UTC(), true, false
+		if c.Request.Method == http.MethodGet {
+			allowed, last = m.resources.View(file, now)
+		} else if meta, ok := m.resources.Get(file); ok {
+			allowed = !meta.Expired(now)
+		}
+		if !allowed {
+			m.logRemoveResource(file)
+			c.Writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if last {
+			// the resource must not be cached since it is gone
+			c.Header("Cache-Control", "no-store")
+			defer m.logRemoveResource(file)
+		}
+		m.serveFile(c, file)
+	}
+}
+
+// serveFile serves the resource of the given path in the repo.
+func (m *Midgard) serveFile(c *gin.Context, file string) {
+	r, info, err := m.repo.Open(file)
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer r.Close()
+	m.setContentHeaders(c, file)
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime, r)
+}
+
+// serveDir serves the requested folder of the repo. Folders are not
+// listed, and an index.html is not displayed either, since HTML is never
+// served inline.
+func (m *Midgard) serveDir(c *gin.Context) {
+	if !strings.HasSuffix(c.Request.URL.Path, "/") {
+		c.Redirect(http.StatusMovedPermanently, path.Base(c.Request.URL.Path)+"/")
+		return
 	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte("<pre>\n</pre>\n"))
 }
 
 // FixPath fixes a relative path
@@ -8,37 +8,127 @@ import (
 	"container/list"
 	"context"
 	"errors"
-	"fmt"
-	"io/fs"
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"os/signal"
-	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
+	"changkun.de/x/midgard/internal/backup"
+	"changkun.de/x/midgard/internal/blob"
+	"changkun.de/x/midgard/internal/blocklist"
 	"changkun.de/x/midgard/internal/config"
-	"changkun.de/x/midgard/internal/utils"
+	"changkun.de/x/midgard/internal/gallery"
+	"changkun.de/x/midgard/internal/lockfile"
+	"changkun.de/x/midgard/internal/namespace"
+	"changkun.de/x/midgard/internal/quota"
+	"changkun.de/x/midgard/internal/resource"
+	"changkun.de/x/midgard/internal/storage"
+	"changkun.de/x/midgard/internal/thumbnail"
+	"changkun.de/x/midgard/internal/token"
+	"changkun.de/x/midgard/internal/upload"
 )
 
 // Midgard is the midgard server that serves all API endpoints.
 type Midgard struct {
-	s *http.Server
+	s        *http.Server
+	accounts map[string]*account // read-only after creation
+	channels map[string]*channel // read-only after creation
+	tokens   *token.Store
+	blocked  *blocklist.Blocklist
+
+	repo      storage.Storage // the allocated resources
+	policy    namespace.Policy
+	backups   *backup.Monitor
+	resources *resource.Index
+	blobs     *blob.Store
+	uploads   *upload.Store
+	quota     *quota.Quota
+	codes     map[string]*gallery.Index // by user, read-only after creation
+
+	thumbnails *thumbnail.Cache
 
 	mu    sync.Mutex
 	users *list.List
+
+	// unlock releases the data folder, it is held while the server runs.
+	unlock func() error
 }
 
+// serverLock is locked by the running server, which guards the data
+// folder against restores.
+const serverLock = dataPath + "/server.lock"
+
 // NewMidgard creates a new midgard server
 func NewMidgard() *Midgard {
-	return &Midgard{users: list.New()}
+	unlock, err := lockfile.Lock(serverLock)
+	if errors.Is(err, lockfile.ErrLocked) {
+		log.Fatalf("data folder is used by another midgard server")
+	}
+	if err != nil {
+		log.Fatalf("cannot lock data folder: %v", err)
+	}
+	tokens, err := token.NewStore("./data/tokens.yml")
+	if err != nil {
+		log.Fatalf("cannot load API tokens: %v", err)
+	}
+	block := config.S().Auth.Block
+	blocked, err := blocklist.New("./data/blocklist.yml", blocklist.Limits{
+		Attempts:   block.Attempts,
+		BlockTime:  time.Duration(block.BlockTime) * time.Second,
+		MaxBlock:   time.Duration(block.MaxBlock) * time.Second,
+		Forget:     time.Duration(block.Forget) * time.Second,
+		MaxEntries: block.MaxEntries,
+	})
+	if err != nil {
+		log.Fatalf("cannot load blocklist: %v", err)
+	}
+	repo := newStorage(config.RepoPath)
+	resources, err := resource.Open(repo, metaDir)
+	if err != nil {
+		log.Fatalf("cannot load resource index: %v", err)
+	}
+	var notifier backup.Notifier
+	if hook := config.S().Store.Backup.Notify.Webhook; hook != "" {
+		notifier = &backup.Webhook{URL: hook, Client: &http.Client{Timeout: 10 * time.Second}}
+	}
+	m := &Midgard{
+		accounts:  newAccounts(),
+		channels:  newChannels(),
+		tokens:    tokens,
+		blocked:   blocked,
+		repo:      repo,
+		policy:    servePolicy(),
+		backups:   backup.NewMonitor(notifier, config.S().Store.Backup.Notify.After),
+		resources: resources,
+		blobs:     blob.New("./data/blobs"),
+		uploads:   upload.NewStore("./data/uploads"),
+		users:     list.New(),
+		unlock:    unlock,
+
+		thumbnails: thumbnail.NewCache(thumbnailLRU),
+	}
+	m.quota = m.newQuota()
+	m.codes = m.newGalleries()
+	return m
 }
 
 // Serve serves Midgard RESTful APIs.
 func (m *Midgard) Serve() {
+	// Restore the universal clipboards so that reconnected daemons
+	// see the same data as before the server restarts.
+	for _, a := range m.accounts {
+		if a.clipboard.Restore() {
+			log.Printf("universal clipboard of %s is restored from history.", a.name)
+		}
+	}
+	for _, ch := range m.channels {
+		if ch.clipboard.Restore() {
+			log.Printf("channel %s is restored from history.", ch.Name)
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	wg := sync.WaitGroup{}
@@ -66,9 +156,20 @@ func (m *Midgard) Serve() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		backup(ctx)
+		m.backup(ctx)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.blocked.Run(ctx)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.sweep(ctx)
 	}()
 	wg.Wait()
+	m.unlock()
 
 	log.Printf("api server is down, good bye!")
 }
@@ -86,168 +187,3 @@ func (m *Midgard) serveHTTP() {
 		log.Printf("close with error: %v", err)
 	}
 }
-
-func init() {
-	if _, err := exec.LookPath("git"); err != nil {
-		panic("please intall git on your system: sudo apt install git")
-	}
-}
-
-// execute executes command inside the data folder.
-func execute(dir, cmd string, args ...string) (out []byte, err error) {
-	c := exec.Command(cmd, args...)
-	c.Dir, err = filepath.Abs(dir)
-	if err != nil {
-		return nil, fmt.Errorf("cannot check your data folder: %v", err)
-	}
-
-	out, err = c.CombinedOutput()
-	return
-}
-
-const backupMsgTimeFmt = "2006-01-02 15:04"
-
-// backup backups the data folder to a configured github repository
-func backup(ctx context.Context) {
-	if !config.S().Store.Backup.Enable {
-		log.Println("backup feature is disabled.")
-		return
-	}
-
-	// initialize data as a git repo if not exists
-	var old = "-old"
-	var haveOld = false
-
-	_, err := os.Stat(config.RepoPath)
-	if !errors.Is(err, os.ErrNotExist) { // repo folder exists
-		// mkdir data/repo-old
-		log.Printf("mkdir %s", config.RepoPath+old)
-		err = os.MkdirAll(config.RepoPath+old, fs.ModeDir|fs.ModePerm)
-		if err != nil {
-			log.Fatalf("cannot rename your folder: %v", err)
-		}
-		// cp -r data/repo data/repo-old
-		log.Printf("cp -r %s %s", config.RepoPath, config.RepoPath+old)
-		err = utils.Copy(config.RepoPath, config.RepoPath+old)
-		if err != nil {
-			log.Fatalf("cannot rename your folder: %v", err)
-		}
-		haveOld = true
-		// rm -rf data/repo
-		log.Printf("rm -rf %s", config.RepoPath)
-		err = os.RemoveAll(config.RepoPath)
-		if err != nil {
-			log.Fatalf("cannot remove all your old files: %v", err)
-		}
-	}
-
-	// git clone https://github.com/changkun/midgard-data repo
-	log.Printf("git clone %s repo", config.S().Store.Backup.Repo)
-	out, err := execute("./data", "git", "clone",
-		config.S().Store.Backup.Repo, "repo")
-	if err != nil {
-		log.Println(utils.BytesToString(out))
-		log.Fatalf("cannot clone your data repo: %v", err)
-	}
-
-	// move everything to the cloned folder
-	// cp -r data/template data/repo
-	repoTmpl := "./data/template"
-	log.Printf("cp -r %s %s", repoTmpl, config.RepoPath)
-	err = utils.Copy(repoTmpl, config.RepoPath)
-	if err != nil {
-		log.Fatalf("failed to merge old data into repo folder: %v", err)
-	}
-	if haveOld {
-		// .git folder may fail to operate(permission denied),
-		// set everything to 755.
-		err := exec.Command("chmod", "-R", "0755", config.RepoPath).Run()
-		if err != nil {
-			log.Fatalf("failed to change permission: %v", err)
-		}
-
-		// cp -r data/repo-old data/repo
-		log.Printf("cp -r %s %s", config.RepoPath+old, config.RepoPath)
-		err = utils.Copy(config.RepoPath+old, config.RepoPath)
-		if err != nil {
-			log.Fatalf("failed to merge old data into repo folder: %v", err)
-		}
-	}
-
-	// seems ok, start commit the local changes
-
-	msg := fmt.Sprintf("midgard: backup %s", time.Now().Format(backupMsgTimeFmt))
-	cmds := [][]string{
-		{"git", "add", "."},
-		{"git", "commit", "-m", msg},
-		{"git", "push"},
-	}
-	for _, cc := range cmds {
-		out, err = execute(config.RepoPath, cc[0], cc[1:]...)
-		if err != nil {
-			if strings.Contains(utils.BytesToString(out), "nothing to commit") ||
-				strings.Contains(utils.BytesToString(out), "no changes added") {
-				log.Println(utils.BytesToString(out))
-				continue
-			}
-			log.Printf("cannot initialize your data folder: %v, details:", err)
-			log.Fatalf("%s: %s\n", strings.Join(cc, " "), utils.BytesToString(out))
-		}
-	}
-
-	err = os.RemoveAll(config.RepoPath + old)
-	if err != nil {
-		log.Fatalf("failed to remove your old data folder: %v", err)
-	}
-	log.Println("backup is enabled.")
-
-	t := time.NewTicker(time.Duration(config.S().Store.Backup.Interval) * time.Minute)
-	for {
-	start:
-		select {
-		case <-ctx.Done():
-			return
-		case <-t.C:
-			// basic conflict resolve, are there any other failures?
-			cmds := [][]string{
-				{"git", "stash"},
-				{"git", "fetch"},
-				{"git", "rebase"},
-				{"git", "stash", "pop"},
-			}
-			for _, cc := range cmds {
-				out, err = execute(config.RepoPath, cc[0], cc[1:]...)
-				if err != nil {
-					if strings.Contains(utils.BytesToString(out), "No stash entries") {
-						continue
-					}
-					log.Printf("failed to resolve conflict: %v, details:", err)
-					log.Printf("%s: %s\n", strings.Join(cc, " "), utils.BytesToString(out))
-					// FIXME: email notification: ask manual action (very rare?)
-					goto start
-				}
-			}
-
-			// add, commit, and push
-			msg := fmt.Sprintf("midgard: backup at %s", time.Now().Format(backupMsgTimeFmt))
-			cmds = [][]string{
-				{"git", "add", "."},
-				{"git", "commit", "-m", msg},
-				{"git", "push"},
-			}
-			for _, cc := range cmds {
-				out, err = execute(config.RepoPath, cc[0], cc[1:]...)
-				if err != nil {
-					if strings.Contains(utils.BytesToString(out), "nothing to commit") {
-						continue
-					}
-					log.Printf("cannot backup your data: %v, details:\n", err)
-					log.Printf("%s: %s\n", strings.Join(cc, " "), utils.BytesToString(out))
-					// FIXME: email notification: ask manual action (very rare?)
-					goto start
-				}
-			}
-			log.Println(msg)
-		}
-	}
-}
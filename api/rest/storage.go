@@ -0,0 +1,71 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"log"
+	"path/filepath"
+
+	"changkun.de/x/midgard/internal/clipboard"
+	"changkun.de/x/midgard/internal/config"
+	"changkun.de/x/midgard/internal/namespace"
+	"changkun.de/x/midgard/internal/storage"
+)
+
+// dataPath is the data folder of midgard, S3 objects are prefixed by
+// their path relative to it, e.g. repo/random/abc.png.
+const dataPath = "./data"
+
+// logsPath is the folder of the clipboard logs of all users and channels.
+const logsPath = dataPath + "/logs/clipboard"
+
+// newStorage returns the configured storage of the given data folder.
+func newStorage(dir string) storage.Storage {
+	switch backend := config.S().Store.Backend; backend {
+	case "", "fs":
+		return storage.NewFS(dir)
+	case "s3":
+		prefix, err := filepath.Rel(dataPath, dir)
+		if err != nil {
+			log.Fatalf("cannot store %s in s3: %v", dir, err)
+		}
+		conf := config.S().Store.S3
+		s, err := storage.NewS3(storage.S3Config{
+			Endpoint:  conf.Endpoint,
+			Region:    conf.Region,
+			Bucket:    conf.Bucket,
+			AccessKey: conf.AccessKey,
+			SecretKey: conf.SecretKey,
+		}, filepath.ToSlash(prefix))
+		if err != nil {
+			log.Fatalf("cannot create s3 storage: %v", err)
+		}
+		return s
+	default:
+		log.Fatalf("unknown storage backend: %s", backend)
+		return nil
+	}
+}
+
+// newClipboard creates a universal clipboard that logs its history in
+// the configured storage of the given data folder.
+func newClipboard(dir string) clipboard.UniversalClipboard {
+	return clipboard.NewUniversalIn(newStorage(dir))
+}
+
+// servePolicy returns the configured policy of serving the repo.
+func servePolicy() namespace.Policy {
+	conf := config.S().Store.Serve
+	switch conf.Default {
+	case "", "allow", "deny":
+	default:
+		log.Fatalf("unknown serve default: %s", conf.Default)
+	}
+	return namespace.Policy{
+		Default: conf.Default != "deny",
+		Allow:   conf.Allow,
+		Deny:    conf.Deny,
+	}
+}
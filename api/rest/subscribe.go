@@ -6,15 +6,14 @@ package rest
 
 import (
 	"container/list"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"sync/atomic"
 
-	"changkun.de/x/midgard/internal/clipboard"
 	"changkun.de/x/midgard/internal/types"
 	"changkun.de/x/midgard/internal/utils"
 	"github.com/gin-gonic/gin"
@@ -26,9 +25,11 @@ var uid uint64 // atomic, incremental
 // user represents a daemon subscriber
 type user struct {
 	sync.Mutex
-	index uint64
-	id    string
-	conn  *websocket.Conn
+	index    uint64
+	id       string
+	account  *account
+	channels map[string]*channel // joined channels, read-only
+	conn     *websocket.Conn
 }
 
 func (d *user) send(msg *types.WebsocketMessage) error {
@@ -97,15 +98,44 @@ func (m *Midgard) Subscribe(c *gin.Context) {
 
 		// register to the subscribers
 		idx := atomic.AddUint64(&uid, 1)
-		u = &user{index: idx, id: wsm.UserID, conn: conn}
+		u = &user{
+			index:    idx,
+			id:       wsm.UserID,
+			account:  m.account(c),
+			channels: map[string]*channel{},
+			conn:     conn,
+		}
+
+		// join the requested channels, older daemons do not send any.
+		var (
+			hs     types.HandshakeData
+			joined types.HandshakeData
+			msg    string
+		)
+		if len(wsm.Data) > 0 {
+			_ = json.Unmarshal(wsm.Data, &hs)
+		}
+		for _, name := range hs.Channels {
+			ch, err := m.join(u.account, name)
+			if err != nil {
+				msg += err.Error() + "; "
+				continue
+			}
+			u.channels[name] = ch
+			joined.Channels = append(joined.Channels, name)
+		}
 		e = m.users.PushBack(u)
 		log.Printf("current daemon subscribers: %d", m.users.Len())
 		m.mu.Unlock()
 
 		// send confirmation
+		data, _ := json.Marshal(joined)
 		err := conn.WriteMessage(
 			websocket.BinaryMessage, (&types.WebsocketMessage{
-				Action: types.ActionHandshakeReady, UserID: u.id,
+				Action:  types.ActionHandshakeReady,
+				UserID:  u.id,
+				Message: strings.TrimSuffix(msg, "; "),
+				Data:    data,
 			}).Encode())
 		if err != nil {
 			log.Printf("failed in register handshake: %v", err)
@@ -188,8 +218,11 @@ func (m *Midgard) handleListDaemons(conn *websocket.Conn, u *user, data []byte)
 	resp := "id\tname\n"
 
 	for e := m.users.Front(); e != nil; e = e.Next() {
-		u := e.Value.(*user)
-		resp += fmt.Sprintf("%d\t%s\n", u.index, u.id)
+		d := e.Value.(*user)
+		if d.account != u.account {
+			continue
+		}
+		resp += fmt.Sprintf("%d\t%s\n", d.index, d.id)
 	}
 
 	return conn.WriteMessage(websocket.BinaryMessage, (&types.WebsocketMessage{
@@ -208,27 +241,28 @@ func (m *Midgard) handleActionClipboardPut(conn *websocket.Conn, u *user, data [
 		}).Encode())
 		return types.ErrBadAction
 	}
-	var raw []byte
-	if b.Type == types.MIMEImagePNG {
-		// We assume the client send us a base64 encoded image data,
-		// Let's decode it into bytes.
-		raw, err = base64.StdEncoding.DecodeString(b.Data)
-		if err != nil {
-			raw = []byte{}
-		}
-	} else {
-		raw = utils.StringToBytes(b.Data)
+	// We assume the client send us base64 encoded data if it is not
+	// plain text. Unknown MIME types are stored as opaque payloads.
+	reps, err := b.Decode()
+	if err != nil {
+		return err
+	}
+
+	cb, err := m.clipboard(u.account, b.Channel)
+	if err != nil {
+		return err
 	}
 
-	updated := clipboard.Universal.Write(b.Type, raw)
+	updated := cb.Put(u.id, reps...)
 	log.Println("universal clipboard has updated, synced from:", u.id)
 	if updated {
 		// Include MIME type information so that the clipboard is
 		// consistent after sync propagation.
-		raw, _ = json.Marshal(b.ClipboardData)
-		m.boardcastMessage(&types.WebsocketMessage{
+		raw, _ := json.Marshal(b.ClipboardData)
+		m.boardcastMessage(u.account, &types.WebsocketMessage{
 			Action:  types.ActionClipboardChanged,
 			UserID:  u.id,
+			Channel: b.Channel,
 			Message: "universal clipboard has changes",
 			Data:    raw, // clipboard data
 		})
@@ -236,7 +270,10 @@ func (m *Midgard) handleActionClipboardPut(conn *websocket.Conn, u *user, data [
 	return nil
 }
 
-func (m *Midgard) boardcastMessage(msg *types.WebsocketMessage) {
+// boardcastMessage sends the given message to all daemons of the given
+// account except the one that sends the message. If the message belongs
+// to a channel, it is sent to all daemons that joined the channel instead.
+func (m *Midgard) boardcastMessage(a *account, msg *types.WebsocketMessage) {
 	log.Println("broadcast message from:", msg.UserID)
 	m.mu.Lock()
 	for e := m.users.Front(); e != nil; e = e.Next() {
@@ -244,6 +281,12 @@ func (m *Midgard) boardcastMessage(msg *types.WebsocketMessage) {
 		if !ok || d.id == msg.UserID {
 			continue
 		}
+		if msg.Channel == "" && d.account != a {
+			continue
+		}
+		if _, joined := d.channels[msg.Channel]; msg.Channel != "" && !joined {
+			continue
+		}
 		log.Println("send message to:", d.id)
 		err := d.send(msg)
 		if err != nil {
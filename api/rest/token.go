@@ -0,0 +1,121 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"changkun.de/x/midgard/internal/token"
+	"changkun.de/x/midgard/internal/types"
+	"github.com/gin-gonic/gin"
+)
+
+// requireScopes rejects requests that are authenticated by an API token
+// which is not granted all the given scopes. Without any scopes, only
+// requests that are authenticated by a password are accepted.
+func requireScopes(scopes ...token.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("midgard_token"); !ok {
+			return // password has all permissions
+		}
+		if len(scopes) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"msg": "this request requires password authentication.",
+			})
+			return
+		}
+		for _, s := range scopes {
+			if !requireScope(c, s) {
+				return
+			}
+		}
+	}
+}
+
+// requireScope is like requireScopes for requests that need the scope
+// only in some cases, e.g. reading the universal clipboard. It aborts
+// the request and returns false if the token is not granted the scope.
+func requireScope(c *gin.Context, s token.Scope) bool {
+	v, ok := c.Get("midgard_token")
+	if !ok || v.(token.Token).Allows(s) {
+		return true
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"msg": fmt.Sprintf("token is not granted the %s scope.", s),
+	})
+	return false
+}
+
+// CreateToken creates a new API token for the authenticated user.
+func (m *Midgard) CreateToken(c *gin.Context) {
+	var in types.CreateTokenInput
+	err := c.ShouldBindJSON(&in)
+	if err != nil {
+		err = fmt.Errorf("cannot bind requested data, err: %w", err)
+		c.JSON(http.StatusBadRequest, types.CreateTokenOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	scopes, err := token.ParseScopes(in.Scopes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.CreateTokenOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	secret, tk, err := m.tokens.Create(m.account(c).name, in.Name, scopes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.CreateTokenOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, types.CreateTokenOutput{
+		ID:      tk.ID,
+		Token:   secret,
+		Message: "token is created, it will not be shown again.",
+	})
+}
+
+// ListTokens lists all API tokens of the authenticated user.
+func (m *Midgard) ListTokens(c *gin.Context) {
+	out := types.ListTokensOutput{
+		Tokens:  []types.TokenInfo{},
+		Message: "success.",
+	}
+	for _, tk := range m.tokens.List(m.account(c).name) {
+		info := types.TokenInfo{ID: tk.ID, Name: tk.Name, Created: tk.Created}
+		for _, s := range tk.Scopes {
+			info.Scopes = append(info.Scopes, string(s))
+		}
+		out.Tokens = append(out.Tokens, info)
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// RevokeToken revokes an API token of the authenticated user.
+func (m *Midgard) RevokeToken(c *gin.Context) {
+	id := c.Param("id")
+	err := m.tokens.Revoke(m.account(c).name, id)
+	if errors.Is(err, token.ErrNotFound) {
+		c.JSON(http.StatusNotFound, types.RevokeTokenOutput{
+			Message: fmt.Sprintf("token %s does not exist.", id),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.RevokeTokenOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, types.RevokeTokenOutput{
+		Message: fmt.Sprintf("token %s is revoked.", id),
+	})
+}
@@ -0,0 +1,219 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"changkun.de/x/midgard/internal/namespace"
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/upload"
+	"github.com/gin-gonic/gin"
+)
+
+// uploadTTL is the duration after which an incomplete upload that is
+// not appended to is dropped.
+const uploadTTL = 24 * time.Hour
+
+// CreateUpload creates a resumable upload session. The data is then
+// uploaded in chunks by AppendUpload, and allocated once complete.
+func (m *Midgard) CreateUpload(c *gin.Context) {
+	var in types.CreateUploadInput
+	err := c.ShouldBindJSON(&in)
+	if err != nil {
+		err = fmt.Errorf("cannot bind requested data, err: %w", err)
+		c.JSON(http.StatusBadRequest, types.UploadOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	if in.Size <= 0 {
+		c.JSON(http.StatusBadRequest, types.UploadOutput{
+			Message: "nothing to persist, no data.",
+		})
+		return
+	}
+
+	if in.URI != "" {
+		if _, err := namespace.Allocate(m.account(c).namespace, in.URI); err != nil {
+			c.JSON(http.StatusBadRequest, types.UploadOutput{
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	// the quota is reserved until the upload is allocated, or dropped.
+	if err := m.quota.Reserve(m.account(c).name, in.Size); err != nil {
+		c.JSON(quotaStatus(err), types.UploadOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+
+	in.Source = types.SourceAttachment
+	ss, err := m.uploads.Create(m.account(c).name, in.Size, in.AllocateURLInput)
+	if err != nil {
+		m.quota.Release(m.account(c).name, in.Size)
+		c.JSON(http.StatusInternalServerError, types.UploadOutput{
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, types.UploadOutput{
+		ID:      ss.ID,
+		Message: "upload is created.",
+	})
+}
+
+// GetUpload returns the offset of an upload session, which is where
+// an interrupted upload continues.
+func (m *Midgard) GetUpload(c *gin.Context) {
+	ss, off, ok := m.upload(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, types.UploadOutput{
+		ID:      ss.ID,
+		Offset:  off,
+		Message: "success.",
+	})
+}
+
+// AppendUpload appends the request body to an upload session at the
+// offset in the Upload-Offset header. The data is allocated once the
+// upload is complete.
+func (m *Midgard) AppendUpload(c *gin.Context) {
+	ss, _, ok := m.upload(c)
+	if !ok {
+		return
+	}
+	off, err := strconv.ParseInt(c.GetHeader(types.HeaderUploadOffset), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, types.UploadOutput{
+			ID:      ss.ID,
+			Message: fmt.Sprintf("invalid %s header.", types.HeaderUploadOffset),
+		})
+		return
+	}
+
+	off, err = m.uploads.Append(ss.ID, off, c.Request.Body)
+	switch {
+	case errors.Is(err, upload.ErrOffsetMismatch), errors.Is(err, upload.ErrCompleting):
+		c.JSON(http.StatusConflict, types.UploadOutput{
+			ID:      ss.ID,
+			Offset:  off,
+			Message: err.Error(),
+		})
+		return
+	case errors.Is(err, upload.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, types.UploadOutput{
+			ID:      ss.ID,
+			Offset:  off,
+			Message: err.Error(),
+		})
+		return
+	case errors.Is(err, upload.ErrNotFound):
+		c.JSON(http.StatusNotFound, types.UploadOutput{
+			Message: err.Error(),
+		})
+		return
+	case err != nil:
+		// the received part of the chunk is kept, the client resumes
+		// from the returned offset.
+		c.JSON(http.StatusInternalServerError, types.UploadOutput{
+			ID:      ss.ID,
+			Offset:  off,
+			Message: err.Error(),
+		})
+		return
+	}
+	if off < ss.Size {
+		c.JSON(http.StatusOK, types.UploadOutput{
+			ID:      ss.ID,
+			Offset:  off,
+			Message: "chunk is received.",
+		})
+		return
+	}
+
+	// the upload is complete, only one request moves the data to the
+	// allocated path, e.g. a retry of a timed out request does not. Its
+	// quota is reserved since the upload is created.
+	err = m.uploads.Complete(ss.ID)
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, upload.ErrCompleting), errors.Is(err, upload.ErrOffsetMismatch):
+			code = http.StatusConflict
+		case errors.Is(err, upload.ErrNotFound):
+			code = http.StatusNotFound
+		}
+		c.JSON(code, types.UploadOutput{
+			ID:      ss.ID,
+			Offset:  off,
+			Message: err.Error(),
+		})
+		return
+	}
+	typ := ss.Alloc.Type
+	if typ == "" {
+		typ, err = m.uploads.Detect(ss.ID)
+		if err != nil {
+			log.Printf("failed to detect type of upload %s: %v", ss.ID, err)
+		}
+	}
+	url, code, err := m.allocate(m.account(c), &ss.Alloc, typ, ss.Size, func(key string) error {
+		return m.repo.Import(key, m.uploads.Path(ss.ID))
+	})
+	// the upload session is finished either way, a failed allocation,
+	// e.g. of an existing URI, cannot be resumed.
+	if err := m.uploads.Remove(ss.ID); err != nil {
+		log.Printf("failed to remove upload %s: %v", ss.ID, err)
+	}
+	if err != nil {
+		c.JSON(code, types.UploadOutput{
+			ID:      ss.ID,
+			Offset:  off,
+			Message: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, types.UploadOutput{
+		ID:      ss.ID,
+		Offset:  off,
+		URL:     url,
+		Message: "success.",
+	})
+}
+
+// upload returns the upload session of the request and its offset.
+// Upload sessions of other users are treated as non-existent. The ID
+// is only returned if the upload session exists.
+func (m *Midgard) upload(c *gin.Context) (upload.Session, int64, bool) {
+	id := c.Param("id")
+	ss, off, err := m.uploads.Get(id)
+	if err == nil && ss.User != m.account(c).name {
+		err = upload.ErrNotFound
+	}
+	if errors.Is(err, upload.ErrNotFound) {
+		c.JSON(http.StatusNotFound, types.UploadOutput{
+			Message: err.Error(),
+		})
+		return upload.Session{}, 0, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, types.UploadOutput{
+			Message: err.Error(),
+		})
+		return upload.Session{}, 0, false
+	}
+	return ss, off, true
+}
@@ -6,17 +6,25 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
 
 	"changkun.de/x/midgard/api/daemon"
 	"changkun.de/x/midgard/internal/types/proto"
+	"changkun.de/x/midgard/internal/utils"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 var (
-	fpath string
+	fpath  string
+	expire string
+	once   bool
+	passwd string
 )
 
 // allocCmd allocate new midgard namespace (aka URL)
@@ -30,22 +38,35 @@ var allocCmd = &cobra.Command{
 		if len(args) > 0 {
 			uri = args[0]
 		}
+		if fpath != "" {
+			upload(uri, fpath)
+			return
+		}
 		allocate(uri, fpath)
 	},
 }
 
 func init() {
 	allocCmd.PersistentFlags().StringVarP(&fpath, "for", "f", "", "path to a file you want to create its public url")
+	allocCmd.PersistentFlags().StringVarP(&expire, "expire", "e", "", "duration after which the url expires, e.g. 1h")
+	allocCmd.PersistentFlags().BoolVar(&once, "once", false, "the url can only be viewed once")
+	allocCmd.PersistentFlags().StringVarP(&passwd, "password", "p", "", "password that visitors of the url need to enter")
 }
 
 // allocate request the midgard daemon to allocate a given URL for
 // a given resource, or the content from the midgard universal clipboard.
 func allocate(dstpath, srcpath string) {
 	daemon.Connect(func(ctx context.Context, c proto.MidgardClient) {
-		out, err := c.AllocateURL(ctx, &proto.AllocateURLInput{
+		in := &proto.AllocateURLInput{
 			DesiredPath: dstpath,
 			SourcePath:  srcpath,
-		})
+			TTL:         expire,
+			Password:    passwd,
+		}
+		if once {
+			in.MaxViews = 1
+		}
+		out, err := c.AllocateURL(ctx, in)
 		if err != nil {
 			log.Fatalf("cannot interact with midgard daemon, err:\n%v",
 				status.Convert(err).Message())
@@ -59,3 +80,112 @@ func allocate(dstpath, srcpath string) {
 		}
 	})
 }
+
+// uploadChunkSize is the size of the data of an upload message, it must
+// be smaller than the message size limit of the daemon.
+const uploadChunkSize = 4 << 20 // 4 MB
+
+// upload requests the midgard daemon to upload the given file in chunks
+// and allocate a given URL for it. The progress is reported to stderr.
+// An interrupted upload resumes if the command is repeated and the file
+// is not modified.
+func upload(dstpath, srcpath string) {
+	info, err := os.Stat(srcpath)
+	if err != nil {
+		log.Fatalf("cannot read %v, err: %v", srcpath, err)
+	}
+	in := &proto.UploadInput{
+		Alloc: &proto.AllocateURLInput{
+			DesiredPath: dstpath,
+			SourcePath:  srcpath,
+			TTL:         expire,
+			Password:    passwd,
+		},
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+	}
+	if once {
+		in.Alloc.MaxViews = 1
+	}
+	daemon.ConnectStream(func(ctx context.Context, c proto.MidgardClient) {
+		url, err := uploadFile(ctx, c, in)
+		if err != nil {
+			// uploads that exceed a limit cannot be resumed.
+			if code := status.Code(err); code == codes.InvalidArgument || code == codes.ResourceExhausted {
+				log.Fatalf("cannot upload %v, err:\n%v", srcpath, status.Convert(err).Message())
+			}
+			log.Fatalf("cannot upload %v, run the command again to resume, err:\n%v",
+				srcpath, status.Convert(err).Message())
+		}
+		fmt.Println(url)
+	})
+}
+
+func uploadFile(ctx context.Context, c proto.MidgardClient, in *proto.UploadInput) (string, error) {
+	stream, err := c.Upload(ctx)
+	if err != nil {
+		return "", err
+	}
+	err = stream.Send(in)
+	if err != nil {
+		return "", err
+	}
+	out, err := stream.Recv()
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(in.Alloc.SourcePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	_, err = f.Seek(out.Offset, io.SeekStart)
+	if err != nil {
+		return "", err
+	}
+	progress(out.Offset, in.Size)
+	defer fmt.Fprintln(os.Stderr)
+
+	buf := make([]byte, uploadChunkSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", err
+		}
+		err = stream.Send(&proto.UploadInput{Data: buf[:n]})
+		if err != nil {
+			return "", err
+		}
+		out, err = stream.Recv()
+		if err != nil {
+			return "", err
+		}
+		progress(out.Offset, in.Size)
+		if out.URL != "" {
+			return out.URL, nil
+		}
+	}
+
+	// the file is shorter than announced, the daemon reports why.
+	err = stream.CloseSend()
+	if err != nil {
+		return "", err
+	}
+	_, err = stream.Recv()
+	if err == nil {
+		err = errors.New("upload is incomplete")
+	}
+	return "", err
+}
+
+// progress prints the progress of an upload.
+func progress(off, size int64) {
+	if size == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\ruploaded %s / %s (%d%%)", utils.ByteSize(off), utils.ByteSize(size), off*100/size)
+}
@@ -30,6 +30,12 @@ See https://changkun.de/s/midgard for more details.
 		allocCmd,
 		statusCmd,
 		code2imgCmd,
+		historyCmd,
+		tokenCmd,
+		lsCmd,
+		rmCmd,
+		mvCmd,
+		quotaCmd,
 	)
 	r.Execute()
 }
@@ -0,0 +1,140 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"changkun.de/x/midgard/api/daemon"
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/types/proto"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	historyLimit  int64
+	historyOffset int64
+	historyOutput string
+)
+
+func init() {
+	historyCmd.PersistentFlags().Int64VarP(&historyLimit, "limit", "n", 20, "number of entries to list")
+	historyCmd.PersistentFlags().Int64VarP(&historyOffset, "offset", "s", 0, "number of newest entries to skip")
+	historyCmd.PersistentFlags().StringVarP(&historyOutput, "output", "o", "", "path to a file to save the shown entry")
+}
+
+// historyCmd browses and restores the universal clipboard history.
+var historyCmd = &cobra.Command{
+	Use:   "history [ls|show N|restore N]",
+	Short: "Browse and restore the universal clipboard history",
+	Long:  `Browse and restore the universal clipboard history`,
+	Args:  cobra.RangeArgs(0, 2),
+	Run: func(_ *cobra.Command, args []string) {
+		action := "ls"
+		if len(args) > 0 {
+			action = args[0]
+		}
+
+		var id uint64
+		switch action {
+		case "ls":
+			if len(args) > 1 {
+				log.Println("ls does not accept arguments")
+				return
+			}
+		case "show", "restore":
+			if len(args) != 2 {
+				log.Printf("%s requires the ID of a history entry", action)
+				return
+			}
+			var err error
+			id, err = strconv.ParseUint(args[1], 10, 64)
+			if err != nil || id == 0 {
+				log.Printf("invalid history entry ID: %v", args[1])
+				return
+			}
+		default:
+			log.Printf("%s is not a valid action", action)
+			return
+		}
+
+		daemon.Connect(func(ctx context.Context, c proto.MidgardClient) {
+			switch action {
+			case "ls":
+				listHistory(ctx, c)
+			case "show":
+				showHistory(ctx, c, id)
+			case "restore":
+				out, err := c.RestoreHistory(ctx, &proto.RestoreHistoryInput{ID: id})
+				if err != nil {
+					log.Println("cannot restore history:", status.Convert(err).Message())
+					return
+				}
+				log.Println(out.Message)
+			}
+		})
+	},
+}
+
+func listHistory(ctx context.Context, c proto.MidgardClient) {
+	out, err := c.ListHistory(ctx, &proto.ListHistoryInput{
+		Offset: historyOffset,
+		Limit:  historyLimit,
+	})
+	if err != nil {
+		log.Println("cannot list history:", status.Convert(err).Message())
+		return
+	}
+
+	log.Printf("%d of %d entries:", len(out.Entries), out.Total)
+	fmt.Println("id\ttime\tsource\tcontent")
+	for _, e := range out.Entries {
+		preview := fmt.Sprintf("<%s, %d bytes>", e.Type, e.Size)
+		if types.MIME(e.Type).IsText() {
+			preview = strings.Join(strings.Fields(string(e.Data)), " ")
+			if r := []rune(preview); len(r) > 50 {
+				preview = string(r[:50]) + "..."
+			}
+		}
+		fmt.Printf("%d\t%s\t%s\t%s\n", e.ID, e.Time, e.Source, preview)
+	}
+}
+
+func showHistory(ctx context.Context, c proto.MidgardClient, id uint64) {
+	out, err := c.ListHistory(ctx, &proto.ListHistoryInput{ID: id})
+	if err != nil {
+		log.Println("cannot show history:", status.Convert(err).Message())
+		return
+	}
+	if len(out.Entries) == 0 {
+		log.Printf("history entry %d does not exist", id)
+		return
+	}
+	e := out.Entries[0]
+
+	if historyOutput != "" {
+		err = os.WriteFile(historyOutput, e.Data, 0644)
+		if err != nil {
+			log.Println("cannot save history entry:", err)
+			return
+		}
+		log.Printf("history entry %d is saved to %s", id, historyOutput)
+		return
+	}
+
+	log.Printf("id: %d, time: %s, source: %s, type: %s, size: %d, hash: %s",
+		e.ID, e.Time, e.Source, e.Type, e.Size, e.Hash)
+	if !types.MIME(e.Type).IsText() {
+		log.Println("use -o to save non-text data to a file.")
+		return
+	}
+	fmt.Println(string(e.Data))
+}
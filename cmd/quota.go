@@ -0,0 +1,58 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+// quotaCmd shows the storage quota of the user.
+var quotaCmd = &cobra.Command{
+	Use:   "quota",
+	Short: "Show the storage usage and quota on the server",
+	Long:  `Show the storage usage and quota of the user on the server`,
+	Args:  cobra.ExactArgs(0),
+	Run: func(_ *cobra.Command, args []string) {
+		res, err := utils.Request(http.MethodGet, types.EndpointQuota, nil)
+		if err != nil {
+			log.Fatalf("cannot request quota: %v", err)
+		}
+		var out types.QuotaOutput
+		err = json.Unmarshal(res, &out)
+		if err != nil {
+			log.Fatalf("cannot parse quota response: %v", err)
+		}
+		if out.User == "" {
+			log.Fatalf("cannot get quota: %s", out.Message)
+		}
+		fmt.Printf("user %s: %s\n", out.User, usageOf(out.Usage, out.Limit))
+		fmt.Printf("server: %s\n", usageOf(out.Total, out.TotalLimit))
+		upload := "unlimited"
+		if out.MaxUpload > 0 {
+			upload = utils.ByteSize(out.MaxUpload)
+		}
+		fmt.Printf("max upload: %s\n", upload)
+	},
+}
+
+// usageOf formats the given usage and its limit.
+func usageOf(u, limit types.QuotaUsage) string {
+	bytes, files := "unlimited", "unlimited"
+	if limit.Bytes > 0 {
+		bytes = utils.ByteSize(limit.Bytes)
+	}
+	if limit.Files > 0 {
+		files = fmt.Sprintf("%d", limit.Files)
+	}
+	return fmt.Sprintf("%s of %s, %d of %s files",
+		utils.ByteSize(u.Bytes), bytes, u.Files, files)
+}
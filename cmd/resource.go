@@ -0,0 +1,93 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"changkun.de/x/midgard/api/daemon"
+	"changkun.de/x/midgard/internal/types/proto"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+)
+
+// lsCmd lists the allocated resources.
+var lsCmd = &cobra.Command{
+	Use:   "ls [path]",
+	Short: "List allocated resources in a folder",
+	Long:  `List allocated resources in a folder, the root folder by default`,
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		p := "/"
+		if len(args) > 0 {
+			p = args[0]
+		}
+		daemon.Connect(func(ctx context.Context, c proto.MidgardClient) {
+			out, err := c.ListResources(ctx, &proto.ListResourcesInput{Path: p})
+			if err != nil {
+				log.Println("cannot list resources:", status.Convert(err).Message())
+				return
+			}
+			fmt.Println("modified\tsize\tpath\tnotes")
+			for _, r := range out.Resources {
+				path, size := r.Path, fmt.Sprintf("%d", r.Size)
+				if r.Dir {
+					path, size = path+"/", "-"
+				}
+				var notes []string
+				if r.Expires != "" {
+					notes = append(notes, "expires "+r.Expires)
+				}
+				if r.MaxViews > 0 {
+					notes = append(notes, fmt.Sprintf("%d/%d views", r.Views, r.MaxViews))
+				}
+				if r.Protected {
+					notes = append(notes, "protected")
+				}
+				fmt.Printf("%s\t%s\t%s\t%s\n", r.ModTime, size, path, strings.Join(notes, ", "))
+			}
+		})
+	},
+}
+
+// rmCmd deletes an allocated resource.
+var rmCmd = &cobra.Command{
+	Use:   "rm path",
+	Short: "Delete an allocated resource or an empty folder",
+	Long:  `Delete an allocated resource or an empty folder`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		daemon.Connect(func(ctx context.Context, c proto.MidgardClient) {
+			out, err := c.DeleteResource(ctx, &proto.DeleteResourceInput{Path: args[0]})
+			if err != nil {
+				log.Println("cannot delete resource:", status.Convert(err).Message())
+				return
+			}
+			log.Println(out.Message)
+		})
+	},
+}
+
+// mvCmd moves an allocated resource.
+var mvCmd = &cobra.Command{
+	Use:   "mv from to",
+	Short: "Move an allocated resource to another path",
+	Long:  `Move an allocated resource to another path, e.g. give a random URL a nicer name`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(_ *cobra.Command, args []string) {
+		daemon.Connect(func(ctx context.Context, c proto.MidgardClient) {
+			out, err := c.MoveResource(ctx, &proto.MoveResourceInput{From: args[0], To: args[1]})
+			if err != nil {
+				log.Println("cannot move resource:", status.Convert(err).Message())
+				return
+			}
+			log.Println(out.Message)
+			fmt.Println(out.URL)
+		})
+	},
+}
@@ -5,10 +5,27 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"log"
+
 	"changkun.de/x/midgard/api/rest"
 	"github.com/spf13/cobra"
 )
 
+var (
+	restoreAt     string
+	restoreDryRun bool
+)
+
+func init() {
+	serverRestoreCmd.Flags().StringVar(&restoreAt, "at", "",
+		"a commit or snapshot, or a time, e.g. 2021-03-01 or 2021-03-01 12:00 (default latest)")
+	serverRestoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false,
+		"only list the changes of the restore")
+	serverCmd.AddCommand(serverRestoreCmd)
+}
+
 // serverCmd runs the midgard server.
 var serverCmd = &cobra.Command{
 	Use:   "server",
@@ -20,3 +37,26 @@ var serverCmd = &cobra.Command{
 		m.Serve()
 	},
 }
+
+var serverRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore the data of the Midgard server from its backup",
+	Long: `Restore the allocated resources, code2img codes, and clipboard
+history of the Midgard server from the configured backup. The server
+must be stopped, a running server is refused.`,
+	Args: cobra.ExactArgs(0),
+	Run: func(_ *cobra.Command, args []string) {
+		rev, changes, err := rest.Restore(context.Background(), restoreAt, restoreDryRun)
+		if err != nil {
+			log.Fatalf("cannot restore backup: %v", err)
+		}
+		for _, c := range changes {
+			fmt.Printf("%s\t%s\n", c.Op, c.Path)
+		}
+		if restoreDryRun {
+			log.Printf("%d changes to restore backup %s, dry run.", len(changes), rev)
+			return
+		}
+		log.Printf("backup %s is restored with %d changes.", rev, len(changes))
+	},
+}
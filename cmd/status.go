@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"changkun.de/x/midgard/api/daemon"
 	"changkun.de/x/midgard/internal/config"
@@ -43,6 +44,7 @@ var statusCmd = &cobra.Command{
 					err)
 			} else {
 				s += fmt.Sprintf("server status: %s\n", term.Green("OK"))
+				s += backupStatus()
 			}
 		}
 
@@ -61,3 +63,43 @@ var statusCmd = &cobra.Command{
 		fmt.Println(s)
 	},
 }
+
+// backupStatus returns the backup status of the server.
+func backupStatus() string {
+	res, err := utils.Request(http.MethodGet, types.EndpointStatus, nil)
+	if err != nil {
+		return fmt.Sprintf("backup status: %s, %v\n", term.Red("request error"), err)
+	}
+	var out types.StatusOutput
+	err = json.Unmarshal(res, &out)
+	if err != nil {
+		return fmt.Sprintf("backup status: %s, details:\n%v\n",
+			term.Red("failed to parse status response from server"), err)
+	}
+	if out.Version == "" {
+		return fmt.Sprintf("backup status: %s, details:\n%s\n",
+			term.Red("failed to get status from server"), out.Message)
+	}
+	b := out.Backup
+	switch {
+	case !b.Enabled:
+		return fmt.Sprintf("backup status: %s\n", term.Orange("disabled"))
+	case b.Failures > 0:
+		return fmt.Sprintf("backup status: %s, %d failures in a row, last success: %s, details:\n%s\n",
+			term.Red("failing"), b.Failures, timeOrNever(b.LastSuccess), b.Error)
+	case b.LastSuccess.IsZero():
+		return fmt.Sprintf("backup status: %s\n", term.Orange("pending"))
+	default:
+		return fmt.Sprintf("backup status: %s, last %s backup at %s, revision %s\n",
+			term.Green("OK"), b.Strategy, timeOrNever(b.LastSuccess), b.Revision)
+	}
+}
+
+// timeOrNever formats the given time in local time, or returns never
+// for a zero time.
+func timeOrNever(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
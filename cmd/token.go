@@ -0,0 +1,102 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/utils"
+	"github.com/spf13/cobra"
+)
+
+var tokenScopes []string
+
+func init() {
+	tokenCreateCmd.Flags().StringSliceVarP(&tokenScopes, "scope", "s", nil,
+		"scopes of the token: clipboard:read, clipboard:write, allocate, code2img")
+	tokenCmd.AddCommand(tokenCreateCmd, tokenListCmd, tokenRevokeCmd)
+}
+
+// tokenCmd manages the API tokens of the midgard server.
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens",
+	Long: `Manage API tokens that grant scoped access to the midgard server,
+e.g. a read-only token for an iOS shortcut.`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create an API token",
+	Long:  `Create an API token, the token is only shown once`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		res, err := utils.Request(http.MethodPost, types.EndpointTokens,
+			&types.CreateTokenInput{Name: args[0], Scopes: tokenScopes})
+		if err != nil {
+			log.Fatalf("cannot create token: %v", err)
+		}
+		var out types.CreateTokenOutput
+		err = json.Unmarshal(res, &out)
+		if err != nil {
+			log.Fatalf("cannot parse response: %v", err)
+		}
+		if out.Token == "" {
+			log.Fatalf("cannot create token: %s", out.Message)
+		}
+		log.Printf("token %s: %s", out.ID, out.Message)
+		fmt.Println(out.Token)
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List API tokens",
+	Long:  `List API tokens`,
+	Args:  cobra.ExactArgs(0),
+	Run: func(_ *cobra.Command, args []string) {
+		res, err := utils.Request(http.MethodGet, types.EndpointTokens, nil)
+		if err != nil {
+			log.Fatalf("cannot list tokens: %v", err)
+		}
+		var out types.ListTokensOutput
+		err = json.Unmarshal(res, &out)
+		if err != nil {
+			log.Fatalf("cannot parse response: %v", err)
+		}
+		if out.Tokens == nil {
+			log.Fatalf("cannot list tokens: %s", out.Message)
+		}
+		fmt.Println("id\tname\tcreated\tscopes")
+		for _, t := range out.Tokens {
+			fmt.Printf("%s\t%s\t%s\t%s\n", t.ID, t.Name,
+				t.Created.Format("2006-01-02 15:04"), strings.Join(t.Scopes, ","))
+		}
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke ID",
+	Short: "Revoke an API token",
+	Long:  `Revoke an API token`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		res, err := utils.Request(http.MethodDelete, types.EndpointTokens+"/"+args[0], nil)
+		if err != nil {
+			log.Fatalf("cannot revoke token: %v", err)
+		}
+		var out types.RevokeTokenOutput
+		err = json.Unmarshal(res, &out)
+		if err != nil {
+			log.Fatalf("cannot parse response: %v", err)
+		}
+		log.Println(out.Message)
+	},
+}
@@ -9,6 +9,7 @@ require (
 	golang.design/x/clipboard v0.7.0
 	golang.design/x/code2img v0.1.4
 	golang.design/x/hotkey v0.4.1
+	golang.org/x/crypto v0.14.0
 	golang.org/x/sys v0.16.0
 	google.golang.org/grpc v1.60.1
 	google.golang.org/protobuf v1.32.0
@@ -46,7 +47,6 @@ require (
 	github.com/ugorji/go/codec v1.2.11 // indirect
 	golang.design/x/mainthread v0.3.0 // indirect
 	golang.org/x/arch v0.3.0 // indirect
-	golang.org/x/crypto v0.14.0 // indirect
 	golang.org/x/exp v0.0.0-20200224162631-6cc2880d07d6 // indirect
 	golang.org/x/image v0.6.0 // indirect
 	golang.org/x/mobile v0.0.0-20230301163155-e0f57694e12c // indirect
@@ -0,0 +1,25 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package backup backs up the data repository of midgard. A backup is
+// either pushed to a git repository, see Git, or archived as tarball
+// snapshots, see Tarball.
+package backup
+
+import (
+	"context"
+	"time"
+)
+
+// Backup is a backup strategy of a folder.
+type Backup interface {
+	// Init prepares the backup, e.g. connects the folder to a remote
+	// repository. It is called before the first backup and may be
+	// retried if it fails.
+	Init(ctx context.Context) error
+	// Run backs up the current state of the folder, the given time
+	// identifies the backup. It returns the revision of the backup,
+	// e.g. the commit hash.
+	Run(ctx context.Context, now time.Time) (string, error)
+}
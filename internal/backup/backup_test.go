@@ -0,0 +1,288 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package backup_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"changkun.de/x/midgard/internal/backup"
+)
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	for _, k := range []string{"AUTHOR", "COMMITTER"} {
+		t.Setenv("GIT_"+k+"_NAME", "midgard")
+		t.Setenv("GIT_"+k+"_EMAIL", "midgard@example.com")
+	}
+	ctx, dir := context.Background(), t.TempDir()
+	remote, work := filepath.Join(dir, "remote.git"), filepath.Join(dir, "work")
+	git(t, dir, "init", "-q", "--bare", remote)
+	git(t, dir, "clone", "-q", remote, work)
+	write(t, filepath.Join(work, "old.txt"), "remote")
+	write(t, filepath.Join(work, "kept.txt"), "remote")
+	git(t, work, "add", ".")
+	git(t, work, "commit", "-q", "-m", "init")
+	git(t, work, "push", "-q", "origin", "HEAD")
+
+	// the local data take precedence over the remote data
+	repo, tmpl := filepath.Join(dir, "repo"), filepath.Join(dir, "template")
+	write(t, filepath.Join(repo, "old.txt"), "local")
+	write(t, filepath.Join(tmpl, "random", ".gitkeep"), "")
+	b := &backup.Git{Dir: repo, Remote: remote, Template: tmpl}
+	if err := b.Init(ctx); err != nil {
+		t.Fatalf("cannot initialize backup: %v", err)
+	}
+	for name, want := range map[string]string{"old.txt": "local", "kept.txt": "remote", "random/.gitkeep": ""} {
+		if got := read(t, filepath.Join(repo, name)); got != want {
+			t.Fatalf("unexpected data of %s: %q, want %q", name, got, want)
+		}
+	}
+
+	// the remote changes are merged with the local changes
+	write(t, filepath.Join(work, "other.txt"), "remote")
+	git(t, work, "add", ".")
+	git(t, work, "commit", "-q", "-m", "other")
+	git(t, work, "push", "-q", "origin", "HEAD")
+	write(t, filepath.Join(repo, "new.txt"), "local")
+	rev, err := b.Run(ctx, time.Now())
+	if err != nil || len(rev) != 40 {
+		t.Fatalf("cannot backup, revision: %q, err: %v", rev, err)
+	}
+	if again, err := b.Run(ctx, time.Now()); err != nil || again != rev {
+		t.Fatalf("cannot backup without changes, revision: %q, err: %v", again, err)
+	}
+	git(t, work, "pull", "-q")
+	for name, want := range map[string]string{"old.txt": "local", "new.txt": "local", "other.txt": "remote"} {
+		if got := read(t, filepath.Join(work, name)); got != want {
+			t.Fatalf("unexpected backup of %s: %q, want %q", name, got, want)
+		}
+	}
+
+	// excluded files are not backed up, and backed up files are removed
+	// once they are excluded
+	secret := filepath.Join(repo, "secret [1].txt")
+	write(t, secret, "local")
+	b.Exclude = func(rel string) bool { return rel == "/secret [1].txt" || rel == "/new.txt" }
+	if rev, err = b.Run(ctx, time.Now()); err != nil {
+		t.Fatalf("cannot backup with excludes: %v", err)
+	}
+	git(t, work, "pull", "-q")
+	for _, name := range []string{"secret [1].txt", "new.txt"} {
+		if _, err := os.Stat(filepath.Join(work, name)); err == nil {
+			t.Fatalf("excluded %s is backed up", name)
+		}
+	}
+	if got := read(t, filepath.Join(repo, "new.txt")); got != "local" {
+		t.Fatalf("excluded file is removed locally: %q", got)
+	}
+	b.Exclude = nil
+	if err := os.Remove(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	// restore the first commit of the remote repository
+	first := strings.TrimSpace(output(t, work, "rev-list", "--max-parents=0", "HEAD"))
+	dst := t.TempDir()
+	if got, err := b.Checkout(ctx, first, dst); err != nil || got != first {
+		t.Fatalf("cannot checkout backup, revision: %q, err: %v", got, err)
+	}
+	changes, err := backup.Diff(repo, dst)
+	if err != nil {
+		t.Fatalf("cannot diff backup: %v", err)
+	}
+	want := []backup.Change{
+		{Op: backup.OpDelete, Path: "/new.txt"},
+		{Op: backup.OpModify, Path: "/old.txt"},
+		{Op: backup.OpDelete, Path: "/other.txt"},
+		{Op: backup.OpDelete, Path: "/random/.gitkeep"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	if err := backup.Apply(repo, dst, changes); err != nil {
+		t.Fatalf("cannot apply backup: %v", err)
+	}
+	if changes, err := backup.Diff(repo, dst); err != nil || len(changes) != 0 {
+		t.Fatalf("backup is not restored, changes: %+v, err: %v", changes, err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "random")); err == nil {
+		t.Fatalf("empty folder is not removed")
+	}
+	if _, err := b.Checkout(ctx, "2000-01-01", t.TempDir()); err == nil {
+		t.Fatalf("backup before the first commit is restored")
+	}
+
+	// a lost folder is restored from the remote repository
+	lost := &backup.Git{Dir: filepath.Join(dir, "lost"), Remote: remote}
+	if got, err := lost.Checkout(ctx, "", t.TempDir()); err != nil || got != rev {
+		t.Fatalf("cannot checkout latest backup, revision: %q, err: %v", got, err)
+	}
+
+	if err := (&backup.Git{Dir: filepath.Join(dir, "x"), Remote: filepath.Join(dir, "missing")}).Init(ctx); err == nil {
+		t.Fatalf("missing remote is initialized")
+	}
+}
+
+func TestTarball(t *testing.T) {
+	ctx, dir := context.Background(), t.TempDir()
+	repo, dest := filepath.Join(dir, "repo"), filepath.Join(dir, "backups")
+	write(t, filepath.Join(repo, "random", "a.txt"), "a")
+	write(t, filepath.Join(repo, "random", "tmp.txt"), "ephemeral")
+	write(t, filepath.Join(repo, ".git", "HEAD"), "ref")
+
+	b := &backup.Tarball{
+		Dir:     repo,
+		Dest:    dest,
+		Keep:    2,
+		Exclude: func(rel string) bool { return rel == "/random/tmp.txt" },
+	}
+	if err := b.Init(ctx); err != nil {
+		t.Fatalf("cannot initialize backup: %v", err)
+	}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		rev, err := b.Run(ctx, now.Add(time.Duration(i)*time.Minute))
+		if err != nil || !strings.HasSuffix(rev, ".tar.gz") {
+			t.Fatalf("cannot backup, revision: %q, err: %v", rev, err)
+		}
+	}
+	snapshots, err := b.Snapshots()
+	if err != nil || len(snapshots) != 2 {
+		t.Fatalf("unexpected snapshots: %v, err: %v", snapshots, err)
+	}
+
+	dst := t.TempDir()
+	if got, err := b.Checkout(ctx, filepath.Base(snapshots[0]), dst); err != nil || got != filepath.Base(snapshots[0]) {
+		t.Fatalf("cannot checkout snapshot, revision: %q, err: %v", got, err)
+	}
+	if changes, err := backup.Diff(filepath.Join(repo, "random"), filepath.Join(dst, "random")); err != nil || len(changes) != 1 {
+		t.Fatalf("unexpected changes: %+v, err: %v", changes, err)
+	}
+	if got, err := b.Checkout(ctx, now.Add(90*time.Second).Format(time.RFC3339), t.TempDir()); err != nil || got != filepath.Base(snapshots[0]) {
+		t.Fatalf("cannot checkout snapshot by time, revision: %q, err: %v", got, err)
+	}
+
+	f, err := os.Open(snapshots[1])
+	if err != nil {
+		t.Fatalf("cannot open snapshot: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("cannot read snapshot: %v", err)
+	}
+	tr := tar.NewReader(zr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("cannot read snapshot: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "random/,random/a.txt" {
+		t.Fatalf("unexpected files in snapshot: %v", got)
+	}
+}
+
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	if out, err := c.CombinedOutput(); err != nil {
+		t.Fatalf("git %s: %v: %s", args[0], err, out)
+	}
+}
+
+func output(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	c := exec.Command("git", args...)
+	c.Dir = dir
+	out, err := c.Output()
+	if err != nil {
+		t.Fatalf("git %s: %v", args[0], err)
+	}
+	return string(out)
+}
+
+func write(t *testing.T, p, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatalf("cannot create folder: %v", err)
+	}
+	if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+		t.Fatalf("cannot write file: %v", err)
+	}
+}
+
+func read(t *testing.T, p string) string {
+	t.Helper()
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatalf("cannot read file: %v", err)
+	}
+	return string(b)
+}
+
+func TestMonitor(t *testing.T) {
+	var msgs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Text   string
+			Status backup.Status
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		msgs = append(msgs, in.Text)
+	}))
+	defer srv.Close()
+
+	ctx, now := context.Background(), time.Now()
+	m := backup.NewMonitor(&backup.Webhook{URL: srv.URL}, 2)
+	m.Start("git")
+	for i := 0; i < 3; i++ {
+		if err := m.Record(ctx, now, "", errors.New("push failed")); err != nil {
+			t.Fatalf("cannot notify: %v", err)
+		}
+	}
+	if s := m.Status(); s.Failures != 3 || s.Error != "push failed" || s.Strategy != "git" {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("repeated failures are not notified once: %q", msgs)
+	}
+	if err := m.Record(ctx, now, "abc", nil); err != nil {
+		t.Fatalf("cannot notify: %v", err)
+	}
+	if s := m.Status(); s.Failures != 0 || s.Revision != "abc" || !s.LastSuccess.Equal(now) {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+	if len(msgs) != 2 || !strings.Contains(msgs[1], "recovered") {
+		t.Fatalf("recovery is not notified: %q", msgs)
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"changkun.de/x/midgard/internal/utils"
+)
+
+// Git backs up a folder by committing its changes and pushing them to a
+// remote git repository.
+type Git struct {
+	Dir    string // the backed up folder
+	Remote string // e.g. https://github.com/changkun/midgard-data.git
+	// Template is an optional folder whose files are copied to the
+	// backed up folder if they do not exist, e.g. .gitkeep files. A
+	// missing template is ignored.
+	Template string
+	// Exclude optionally excludes files from the backup, it receives
+	// slash separated paths relative to the backed up folder, e.g.
+	// /random/abc.png. The excludes are evaluated on each run and are
+	// written to .git/info/exclude, excluded files that were backed up
+	// before are removed from later commits.
+	Exclude func(rel string) bool
+}
+
+var (
+	_ Backup   = &Git{}
+	_ Restorer = &Git{}
+)
+
+const commitTimeFormat = "2006-01-02 15:04"
+
+// Init turns the folder into a clone of the remote repository. Existing
+// files of the folder are kept and take precedence over the files of
+// the remote repository.
+func (g *Git) Init(ctx context.Context) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return errors.New("git is not installed")
+	}
+	err := os.MkdirAll(g.Dir, fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return fmt.Errorf("cannot create folder: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(g.Dir, ".git")); err != nil {
+		if err := g.clone(ctx); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(g.Template); g.Template != "" && err == nil {
+		err = copyMissing(g.Template, g.Dir)
+		if err != nil {
+			return fmt.Errorf("cannot copy template: %w", err)
+		}
+	}
+	return nil
+}
+
+// clone clones the remote repository without checking out its files,
+// and moves the clone into the folder. Files of the remote repository
+// that do not exist in the folder are checked out afterwards.
+func (g *Git) clone(ctx context.Context) error {
+	tmp := filepath.Clean(g.Dir) + ".clone"
+	err := os.RemoveAll(tmp)
+	if err != nil {
+		return fmt.Errorf("cannot remove stale clone: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	_, err = g.git(ctx, filepath.Dir(tmp), "clone", "--no-checkout", g.Remote, filepath.Base(tmp))
+	if err != nil {
+		return err
+	}
+	err = os.Rename(filepath.Join(tmp, ".git"), filepath.Join(g.Dir, ".git"))
+	if err != nil {
+		return fmt.Errorf("cannot move clone: %w", err)
+	}
+	if !g.hasCommit(ctx, "HEAD") {
+		return nil // the remote repository is empty
+	}
+	if _, err := g.git(ctx, g.Dir, "reset", "-q"); err != nil {
+		return err
+	}
+	out, err := g.git(ctx, g.Dir, "ls-files", "-z", "--deleted")
+	if err != nil {
+		return err
+	}
+	files := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	if len(files) == 0 || files[0] == "" {
+		return nil
+	}
+	_, err = g.git(ctx, g.Dir, append([]string{"checkout", "--"}, files...)...)
+	return err
+}
+
+// Run commits all changes of the folder, rebases them onto the remote
+// repository, and pushes them. The revision is the pushed commit hash.
+func (g *Git) Run(ctx context.Context, now time.Time) (string, error) {
+	if g.hasCommit(ctx, "@{upstream}") {
+		err := g.sync(ctx)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := g.exclude(ctx); err != nil {
+		return "", err
+	}
+	msg := fmt.Sprintf("midgard: backup at %s", now.Format(commitTimeFormat))
+	if _, err := g.git(ctx, g.Dir, "add", "."); err != nil {
+		return "", err
+	}
+	out, err := g.git(ctx, g.Dir, "commit", "-m", msg)
+	if err != nil && !strings.Contains(out, "nothing to commit") {
+		return "", err
+	}
+	if _, err := g.git(ctx, g.Dir, "push", "-u", "origin", "HEAD"); err != nil {
+		return "", err
+	}
+	out, err = g.git(ctx, g.Dir, "rev-parse", "HEAD")
+	return strings.TrimSpace(out), err
+}
+
+// exclude rewrites the exclude file of the local repository from the
+// Exclude func, and untracks the excluded files.
+func (g *Git) exclude(ctx context.Context) error {
+	var files []string
+	if g.Exclude != nil {
+		err := filepath.WalkDir(g.Dir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(g.Dir, p)
+			if err != nil {
+				return err
+			}
+			rel = path.Join("/", filepath.ToSlash(rel))
+			if rel == "/.git" {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && g.Exclude(rel) {
+				files = append(files, rel)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("cannot list excluded files: %w", err)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("# generated by midgard on each backup, do not edit.\n")
+	for _, f := range files {
+		b.WriteString(excludePattern(f) + "\n")
+	}
+	p := filepath.Join(g.Dir, ".git", "info", "exclude")
+	err := os.MkdirAll(filepath.Dir(p), fs.ModeDir|fs.ModePerm)
+	if err == nil {
+		err = os.WriteFile(p, []byte(b.String()), 0644)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot write exclude file: %w", err)
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	args := []string{"--literal-pathspecs", "rm", "-q", "--cached", "--ignore-unmatch", "--"}
+	for _, f := range files {
+		args = append(args, strings.TrimPrefix(f, "/"))
+	}
+	_, err = g.git(ctx, g.Dir, args...)
+	return err
+}
+
+// excludePattern returns the gitignore pattern that matches exactly the
+// file of the given slash separated path.
+func excludePattern(rel string) string {
+	var b strings.Builder
+	for i, r := range rel {
+		if strings.ContainsRune(`\*?[`, r) || (r == ' ' && i == len(rel)-1) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// sync rebases the local commits onto the remote repository, local
+// changes are stashed in the meantime.
+func (g *Git) sync(ctx context.Context) error {
+	out, err := g.git(ctx, g.Dir, "stash")
+	if err != nil {
+		return err
+	}
+	stashed := !strings.Contains(out, "No local changes to save")
+
+	_, err = g.git(ctx, g.Dir, "fetch")
+	if err == nil {
+		_, err = g.git(ctx, g.Dir, "rebase")
+		if err != nil {
+			g.git(ctx, g.Dir, "rebase", "--abort")
+		}
+	}
+	if stashed {
+		if _, err1 := g.git(ctx, g.Dir, "stash", "pop"); err == nil {
+			err = err1
+		}
+	}
+	return err
+}
+
+// Checkout extracts the backup at the given point from the remote
+// repository. The point is either a commit or a time.
+func (g *Git) Checkout(ctx context.Context, at, dst string) (string, error) {
+	gitDir, ref := filepath.Join(g.Dir, ".git"), "HEAD"
+	if _, err := os.Stat(gitDir); err == nil {
+		if _, err := g.git(ctx, g.Dir, "fetch"); err != nil {
+			return "", err
+		}
+		if g.hasCommit(ctx, "@{upstream}") {
+			ref = "@{upstream}"
+		}
+	} else {
+		// the folder is lost, restore from a fresh clone.
+		tmp, err := os.MkdirTemp("", "midgard-restore-*")
+		if err != nil {
+			return "", fmt.Errorf("cannot create temporary folder: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		if _, err := g.git(ctx, "", "clone", "--bare", g.Remote, tmp); err != nil {
+			return "", err
+		}
+		gitDir = tmp
+	}
+
+	rev := ref
+	if t, ok := ParseTime(at); ok {
+		out, err := g.git(ctx, "", "--git-dir="+gitDir, "rev-list", "-1", "--before="+t.Format(time.RFC3339), ref)
+		if err != nil {
+			return "", err
+		}
+		rev = strings.TrimSpace(out)
+		if rev == "" {
+			return "", fmt.Errorf("no backup before %s", at)
+		}
+	} else if at != "" {
+		rev = at
+	}
+	out, err := g.git(ctx, "", "--git-dir="+gitDir, "rev-parse", "--verify", "-q", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown backup revision: %s", at)
+	}
+	rev = strings.TrimSpace(out)
+
+	c := exec.CommandContext(ctx, "git", "--git-dir="+gitDir, "archive", "--format=tar", rev)
+	r, err := c.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	var stderr strings.Builder
+	c.Stderr = &stderr
+	if err := c.Start(); err != nil {
+		return "", fmt.Errorf("git archive: %w", err)
+	}
+	err = extract(r, dst)
+	io.Copy(io.Discard, r) // let git exit if extract fails
+	if err1 := c.Wait(); err1 != nil {
+		return "", fmt.Errorf("git archive: %w: %s", err1, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot extract backup: %w", err)
+	}
+	return rev, nil
+}
+
+// hasCommit reports whether the given revision refers to a commit.
+func (g *Git) hasCommit(ctx context.Context, rev string) bool {
+	_, err := g.git(ctx, g.Dir, "rev-parse", "--verify", "-q", rev)
+	return err == nil
+}
+
+// git runs a git command in the given folder and returns its output.
+func (g *Git) git(ctx context.Context, dir string, args ...string) (string, error) {
+	c := exec.CommandContext(ctx, "git", args...)
+	c.Dir = dir
+	b, err := c.CombinedOutput()
+	out := utils.BytesToString(b)
+	if err != nil {
+		return out, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(out))
+	}
+	return out, nil
+}
+
+// copyMissing copies the files of the src folder to the dst folder if
+// they do not exist in the dst folder.
+func copyMissing(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if _, err := os.Lstat(target); err == nil {
+			return nil
+		}
+		return utils.Copy(p, target)
+	})
+}
@@ -0,0 +1,234 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Restorer is implemented by backups that can be restored.
+type Restorer interface {
+	// Checkout extracts the backup at the given point into the given
+	// empty folder, and returns the revision of the backup. The point
+	// is either a revision or a time, see ParseTime, an empty point
+	// refers to the latest backup.
+	Checkout(ctx context.Context, at, dst string) (string, error)
+}
+
+// ParseTime parses a point in time of a backup, e.g. 2021-03-01,
+// 2021-03-01 12:00 in local time, or RFC3339.
+func ParseTime(at string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, at, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Op is an operation of a change.
+type Op string
+
+// Operations of changes.
+const (
+	OpAdd    Op = "A"
+	OpModify Op = "M"
+	OpDelete Op = "D"
+)
+
+// Change is a change of a file that restores a backup.
+type Change struct {
+	Op   Op
+	Path string // slash separated and relative to the folder, e.g. /random/abc.png
+}
+
+// Diff returns the changes that turn the dir folder into the src folder,
+// sorted by their paths. The .git folder is ignored.
+func Diff(dir, src string) ([]Change, error) {
+	have, err := files(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	want, err := files(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for p := range want {
+		if _, ok := have[p]; !ok {
+			changes = append(changes, Change{OpAdd, p})
+			continue
+		}
+		same, err := sameFile(filepath.Join(dir, filepath.FromSlash(p)), filepath.Join(src, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			changes = append(changes, Change{OpModify, p})
+		}
+	}
+	for p := range have {
+		if _, ok := want[p]; !ok {
+			changes = append(changes, Change{OpDelete, p})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Apply applies the changes of Diff to the dir folder.
+func Apply(dir, src string, changes []Change) error {
+	for _, c := range changes {
+		p := filepath.Join(dir, filepath.FromSlash(c.Path))
+		switch c.Op {
+		case OpDelete:
+			if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("cannot delete %s: %w", c.Path, err)
+			}
+			// remove the folders that become empty, except the root.
+			for d := filepath.Dir(p); d != filepath.Clean(dir) && os.Remove(d) == nil; d = filepath.Dir(d) {
+			}
+		case OpAdd, OpModify:
+			if err := copyFile(filepath.Join(src, filepath.FromSlash(c.Path)), p); err != nil {
+				return fmt.Errorf("cannot restore %s: %w", c.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// files returns the slash separated paths of the regular files in the
+// given folder, except the .git folder.
+func files(dir string) (map[string]bool, error) {
+	all := map[string]bool{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = path.Join("/", filepath.ToSlash(rel))
+		if rel == "/.git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			all[rel] = true
+		}
+		return nil
+	})
+	return all, err
+}
+
+func sameFile(a, b string) (bool, error) {
+	ha, err := hashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hb, err := hashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ha, hb), nil
+}
+
+func hashFile(p string) ([]byte, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// copyFile replaces the dst file by a copy of the src file. Existing
+// hard links of the dst file are not affected.
+func copyFile(src, dst string) error {
+	err := os.MkdirAll(filepath.Dir(dst), fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(dst), ".restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name()) // no-op after the rename
+	_, err = io.Copy(out, in)
+	if err1 := out.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Chmod(out.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(out.Name(), dst)
+}
+
+// extract extracts the regular files and folders of a tarball to the
+// given folder.
+func extract(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// a cleaned absolute path never escapes the folder.
+		p := filepath.Join(dst, filepath.FromSlash(path.Clean("/"+hdr.Name)))
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(p, fs.ModeDir|fs.ModePerm)
+		case tar.TypeReg:
+			err = writeFile(p, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeFile(p string, r io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(p), fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
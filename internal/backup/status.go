@@ -0,0 +1,135 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status is the status of the backups.
+type Status struct {
+	Strategy    string    `json:"strategy"` // empty if backup is disabled
+	LastSuccess time.Time `json:"last_success"`
+	LastFailure time.Time `json:"last_failure"`
+	Revision    string    `json:"revision"` // of the last successful backup
+	Error       string    `json:"error"`    // of the last failed backup
+	Failures    int       `json:"failures"` // consecutive failures
+}
+
+// Notifier notifies about the backup status.
+type Notifier interface {
+	Notify(ctx context.Context, msg string, s Status) error
+}
+
+// Monitor records the results of backups. If backups fail repeatedly,
+// the notifier is notified once, and once more after they recover.
+type Monitor struct {
+	notifier Notifier // may be nil
+	after    int      // consecutive failures before notifying
+
+	mu       sync.Mutex
+	status   Status
+	notified bool
+}
+
+// NewMonitor creates a monitor that notifies the given notifier after
+// the given number of consecutive failures.
+func NewMonitor(n Notifier, after int) *Monitor {
+	if after <= 0 {
+		after = 3
+	}
+	return &Monitor{notifier: n, after: after}
+}
+
+// Start marks the backup of the given strategy as enabled.
+func (m *Monitor) Start(strategy string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status.Strategy = strategy
+}
+
+// Status returns the current backup status.
+func (m *Monitor) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Record records the result of a backup at the given time, and returns
+// the error of the notification if it is sent but fails.
+func (m *Monitor) Record(ctx context.Context, now time.Time, rev string, err error) error {
+	m.mu.Lock()
+	var msg string
+	if err != nil {
+		m.status.LastFailure = now
+		m.status.Error = err.Error()
+		m.status.Failures++
+		if m.status.Failures >= m.after && !m.notified {
+			m.notified = true
+			msg = fmt.Sprintf("midgard backup failed %d times in a row: %v", m.status.Failures, err)
+		}
+	} else {
+		m.status.LastSuccess = now
+		m.status.Revision = rev
+		m.status.Error = ""
+		m.status.Failures = 0
+		if m.notified {
+			m.notified = false
+			msg = fmt.Sprintf("midgard backup is recovered at revision %s", rev)
+		}
+	}
+	s := m.status
+	m.mu.Unlock()
+
+	if msg == "" || m.notifier == nil {
+		return nil
+	}
+	return m.notifier.Notify(ctx, msg, s)
+}
+
+// Webhook notifies by posting a JSON message to a URL. The message has
+// a text field, which is compatible with the webhooks of Slack and
+// Mattermost, and the status of the backups.
+type Webhook struct {
+	URL    string
+	Client *http.Client // http.DefaultClient if nil
+}
+
+var _ Notifier = &Webhook{}
+
+// Notify posts the message and the status to the webhook.
+func (w *Webhook) Notify(ctx context.Context, msg string, s Status) error {
+	body, err := json.Marshal(struct {
+		Text   string `json:"text"`
+		Status Status `json:"status"`
+	}{msg, s})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c := w.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot notify webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("cannot notify webhook: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tarball backs up a folder as gzipped tarball snapshots in another
+// folder, the snapshots are named midgard-<time>.tar.gz.
+type Tarball struct {
+	Dir  string // the backed up folder
+	Dest string // the folder of the snapshots
+	Keep int    // number of kept snapshots, zero keeps all
+	// Exclude optionally excludes files from the snapshots, it receives
+	// slash separated paths relative to the backed up folder, e.g.
+	// /random/abc.png. The .git folder is always excluded.
+	Exclude func(rel string) bool
+}
+
+var (
+	_ Backup   = &Tarball{}
+	_ Restorer = &Tarball{}
+)
+
+const (
+	tarballPrefix     = "midgard-"
+	tarballExt        = ".tar.gz"
+	tarballTimeFormat = "20060102-150405"
+)
+
+// Init creates the folder of the snapshots.
+func (t *Tarball) Init(ctx context.Context) error {
+	err := os.MkdirAll(t.Dest, fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return fmt.Errorf("cannot create backup folder: %w", err)
+	}
+	return nil
+}
+
+// Run creates a new snapshot of the folder and removes the snapshots
+// that exceed the number of kept snapshots. The revision is the file
+// name of the snapshot.
+func (t *Tarball) Run(ctx context.Context, now time.Time) (string, error) {
+	f, err := os.CreateTemp(t.Dest, ".snapshot-*")
+	if err != nil {
+		return "", fmt.Errorf("cannot create snapshot: %w", err)
+	}
+	defer os.Remove(f.Name()) // no-op after the rename
+
+	err = t.archive(ctx, f)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return "", fmt.Errorf("cannot create snapshot: %w", err)
+	}
+	name := tarballPrefix + now.UTC().Format(tarballTimeFormat) + tarballExt
+	err = os.Rename(f.Name(), filepath.Join(t.Dest, name))
+	if err != nil {
+		return "", fmt.Errorf("cannot save snapshot: %w", err)
+	}
+	return name, t.prune()
+}
+
+// archive writes the gzipped tarball of the folder to w.
+func (t *Tarball) archive(ctx context.Context, w io.Writer) error {
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	err := filepath.WalkDir(t.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(t.Dir, p)
+		if err != nil {
+			return err
+		}
+		rel = path.Join("/", filepath.ToSlash(rel))
+		if rel == "/.git" {
+			return filepath.SkipDir
+		}
+		if rel == "/" || (t.Exclude != nil && t.Exclude(rel)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil // e.g. symlinks
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = strings.TrimPrefix(rel, "/")
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.CopyN(tw, f, hdr.Size)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Snapshots returns the paths of all snapshots, oldest first.
+func (t *Tarball) Snapshots() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(t.Dest, tarballPrefix+"*"+tarballExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Checkout extracts the snapshot at the given point. The point is either
+// the name of a snapshot, or a time that selects the latest snapshot
+// before it.
+func (t *Tarball) Checkout(ctx context.Context, at, dst string) (string, error) {
+	files, err := t.Snapshots()
+	if err != nil {
+		return "", fmt.Errorf("cannot list snapshots: %w", err)
+	}
+	var snapshot string
+	if before, ok := ParseTime(at); ok {
+		for _, f := range files {
+			name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(f), tarballPrefix), tarballExt)
+			created, err := time.Parse(tarballTimeFormat, name)
+			if err == nil && !created.After(before) {
+				snapshot = f
+			}
+		}
+	} else if at != "" {
+		for _, f := range files {
+			if name := filepath.Base(f); name == at || name == at+tarballExt {
+				snapshot = f
+			}
+		}
+	} else if len(files) > 0 {
+		snapshot = files[len(files)-1]
+	}
+	if snapshot == "" {
+		return "", fmt.Errorf("no snapshot at %q", at)
+	}
+
+	f, err := os.Open(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("cannot open snapshot: %w", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return "", fmt.Errorf("cannot read snapshot: %w", err)
+	}
+	if err := extract(zr, dst); err != nil {
+		return "", fmt.Errorf("cannot extract snapshot: %w", err)
+	}
+	return filepath.Base(snapshot), nil
+}
+
+// prune removes the oldest snapshots that exceed the number of kept
+// snapshots.
+func (t *Tarball) prune() error {
+	if t.Keep <= 0 {
+		return nil
+	}
+	files, err := t.Snapshots()
+	if err != nil {
+		return fmt.Errorf("cannot list snapshots: %w", err)
+	}
+	for len(files) > t.Keep {
+		if err := os.Remove(files[0]); err != nil {
+			return fmt.Errorf("cannot remove old snapshot: %w", err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
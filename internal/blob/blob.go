@@ -0,0 +1,129 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package blob implements a content-addressed store of files. Files of
+// identical content share a single blob by hard links, so that a
+// duplicated file takes no additional storage.
+//
+// The store does not count references of blobs, the owner of the store
+// removes a blob once no file refers to it anymore.
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrInvalidHash indicates that a hash is not a blob hash.
+var ErrInvalidHash = errors.New("invalid blob hash")
+
+// Store is a content-addressed store of files in a folder.
+type Store struct {
+	mu  sync.Mutex // serializes changes of blobs
+	dir string
+}
+
+// New creates a store in the given folder. The folder must be on the
+// same file system as the files that are added to the store.
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Hash returns the hash of the content of the file of the given path.
+func Hash(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("cannot open file: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return "", fmt.Errorf("cannot hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Add adds the file of the given path to the store and returns the hash
+// of its content. If a blob of the same content exists, the file is
+// replaced by a hard link to the blob, otherwise the file becomes the
+// blob of its content.
+func (s *Store) Add(p string) (string, error) {
+	hash, err := Hash(p)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := s.Path(hash)
+	if _, err := os.Stat(b); err != nil {
+		err = os.MkdirAll(filepath.Dir(b), fs.ModeDir|fs.ModePerm)
+		if err != nil {
+			return "", fmt.Errorf("cannot create blob folder: %w", err)
+		}
+		err = os.Link(p, b)
+		if err != nil {
+			return "", fmt.Errorf("cannot create blob: %w", err)
+		}
+		return hash, nil
+	}
+
+	// replace the file atomically, so that the file never disappears.
+	tmp := p + ".blob"
+	err = os.Link(b, tmp)
+	if err != nil {
+		return "", fmt.Errorf("cannot link blob: %w", err)
+	}
+	err = os.Rename(tmp, p)
+	if err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("cannot link blob: %w", err)
+	}
+	return hash, nil
+}
+
+// Remove removes the blob of the given hash. Files that are linked to
+// the blob are not affected.
+func (s *Store) Remove(hash string) error {
+	if !valid(hash) {
+		return ErrInvalidHash
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.Path(hash))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("cannot remove blob: %w", err)
+	}
+	return nil
+}
+
+// Path returns the path of the blob of the given hash, blobs are spread
+// to folders by the first two characters of their hash.
+func (s *Store) Path(hash string) string {
+	if len(hash) < 2 {
+		return filepath.Join(s.dir, hash)
+	}
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// valid reports whether the given hash is a valid blob hash, which
+// cannot escape the store folder.
+func valid(hash string) bool {
+	if len(hash) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package blob_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"changkun.de/x/midgard/internal/blob"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	s := blob.New(filepath.Join(dir, "blobs"))
+
+	a, b := filepath.Join(dir, "a.png"), filepath.Join(dir, "b.png")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("screenshot"), 0644); err != nil {
+			t.Fatalf("cannot write file: %v", err)
+		}
+	}
+	ha, err := s.Add(a)
+	if err != nil {
+		t.Fatalf("cannot add file: %v", err)
+	}
+	hb, err := s.Add(b)
+	if err != nil {
+		t.Fatalf("cannot add file: %v", err)
+	}
+	if ha != hb {
+		t.Fatalf("identical files have different hashes: %v, %v", ha, hb)
+	}
+
+	// identical files share the storage of the blob
+	ia, _ := os.Stat(a)
+	ib, _ := os.Stat(b)
+	iblob, err := os.Stat(s.Path(ha))
+	if err != nil || !os.SameFile(ia, ib) || !os.SameFile(ia, iblob) {
+		t.Fatalf("identical files are not deduplicated, err: %v", err)
+	}
+
+	// removing the blob does not affect the files
+	if err := s.Remove(ha); err != nil {
+		t.Fatalf("cannot remove blob: %v", err)
+	}
+	if data, err := os.ReadFile(b); err != nil || string(data) != "screenshot" {
+		t.Fatalf("file is affected by blob removal: %q, err: %v", data, err)
+	}
+	if err := s.Remove("../../a.png"); err == nil {
+		t.Fatalf("invalid hash is accepted")
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package blocklist implements a persisted and bounded list of IPs
+// that failed to authenticate too often.
+//
+// An IP is blocked once it fails more than the allowed attempts. After
+// the block expires the IP gets another chance, but the next block lasts
+// twice as long. Entries that are not blocked and have not failed for a
+// while are forgotten in the background.
+package blocklist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Limits are the limits of a blocklist.
+type Limits struct {
+	Attempts   int           // failures that are allowed before blocking
+	BlockTime  time.Duration // duration of the first block
+	MaxBlock   time.Duration // maximum duration of a block
+	Forget     time.Duration // forget entries that did not fail for this long
+	MaxEntries int           // maximum number of tracked IPs
+	GCInterval time.Duration // interval of garbage collection and persistence
+}
+
+// DefaultLimits are the limits that are used if a limit is not specified.
+var DefaultLimits = Limits{
+	Attempts:   5,
+	BlockTime:  10 * time.Second,
+	MaxBlock:   24 * time.Hour,
+	Forget:     24 * time.Hour,
+	MaxEntries: 10000,
+	GCInterval: time.Minute,
+}
+
+// Entry is the failure record of an IP.
+type Entry struct {
+	IP        string        `yaml:"ip"`
+	Failures  int           `yaml:"failures"`
+	LastFail  time.Time     `yaml:"last_fail"`
+	BlockTime time.Duration `yaml:"block_time"`
+}
+
+// BlockedUntil returns the time until the IP is blocked, or the zero
+// time if the IP is not blocked.
+func (e Entry) BlockedUntil(l Limits) time.Time {
+	if e.Failures <= l.Attempts {
+		return time.Time{}
+	}
+	return e.LastFail.Add(e.BlockTime)
+}
+
+// Blocklist is a list of IPs that failed to authenticate.
+type Blocklist struct {
+	mu      sync.Mutex
+	path    string
+	limits  Limits
+	entries map[string]*Entry
+	dirty   bool
+}
+
+// New creates a blocklist with the given limits and loads the entries
+// that are persisted in the given file if it exists. Zero limits are
+// replaced by the DefaultLimits.
+func New(path string, limits Limits) (*Blocklist, error) {
+	if limits.Attempts <= 0 {
+		limits.Attempts = DefaultLimits.Attempts
+	}
+	if limits.BlockTime <= 0 {
+		limits.BlockTime = DefaultLimits.BlockTime
+	}
+	if limits.MaxBlock <= 0 {
+		limits.MaxBlock = DefaultLimits.MaxBlock
+	}
+	if limits.Forget <= 0 {
+		limits.Forget = DefaultLimits.Forget
+	}
+	if limits.MaxEntries <= 0 {
+		limits.MaxEntries = DefaultLimits.MaxEntries
+	}
+	if limits.GCInterval <= 0 {
+		limits.GCInterval = DefaultLimits.GCInterval
+	}
+
+	b := &Blocklist{path: path, limits: limits, entries: map[string]*Entry{}}
+	if path == "" {
+		return b, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read blocklist: %w", err)
+	}
+	var entries []Entry
+	err = yaml.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse blocklist: %w", err)
+	}
+	for i := range entries {
+		b.entries[entries[i].IP] = &entries[i]
+	}
+	return b, nil
+}
+
+// Limits returns the limits of the blocklist.
+func (b *Blocklist) Limits() Limits {
+	return b.limits
+}
+
+// Blocked reports whether the given IP is blocked at the given time.
+// If the block of the IP has expired, the IP gets another chance, but
+// the next block lasts twice as long.
+func (b *Blocklist) Blocked(ip string, now time.Time) (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entries[ip]
+	if !ok {
+		return time.Time{}, false
+	}
+	until := e.BlockedUntil(b.limits)
+	if until.IsZero() {
+		return time.Time{}, false
+	}
+	if now.Before(until) {
+		return until, true
+	}
+
+	// clear the failcount, but increase the next block time
+	e.Failures = 0
+	e.BlockTime *= 2
+	if e.BlockTime > b.limits.MaxBlock {
+		e.BlockTime = b.limits.MaxBlock
+	}
+	b.dirty = true
+	return time.Time{}, false
+}
+
+// Fail records a failed attempt of the given IP at the given time.
+func (b *Blocklist) Fail(ip string, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dirty = true
+	if e, ok := b.entries[ip]; ok {
+		e.Failures++
+		e.LastFail = now
+		return
+	}
+	// a full list of blocked IPs does not track new IPs.
+	if len(b.entries) >= b.limits.MaxEntries && !b.evict(now) {
+		return
+	}
+	b.entries[ip] = &Entry{
+		IP:        ip,
+		Failures:  1,
+		LastFail:  now,
+		BlockTime: b.limits.BlockTime,
+	}
+}
+
+// List returns all entries, the most recent failure comes first.
+func (b *Blocklist) List() []Entry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.list()
+}
+
+func (b *Blocklist) list() []Entry {
+	entries := make([]Entry, 0, len(b.entries))
+	for _, e := range b.entries {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastFail.After(entries[j].LastFail)
+	})
+	return entries
+}
+
+// Clear removes the given IP from the blocklist and reports whether it
+// was in the blocklist. If ip is empty, all IPs are removed.
+func (b *Blocklist) Clear(ip string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dirty = true
+	if ip == "" {
+		b.entries = map[string]*Entry{}
+		return true
+	}
+	_, ok := b.entries[ip]
+	delete(b.entries, ip)
+	return ok
+}
+
+// GC forgets all entries that are not blocked and did not fail for a
+// while at the given time.
+func (b *Blocklist) GC(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ip, e := range b.entries {
+		if b.expired(e, now) {
+			delete(b.entries, ip)
+			b.dirty = true
+		}
+	}
+}
+
+func (b *Blocklist) expired(e *Entry, now time.Time) bool {
+	if now.Before(e.BlockedUntil(b.limits)) {
+		return false
+	}
+	return now.Sub(e.LastFail) > b.limits.Forget
+}
+
+// evict makes room for a new entry and reports whether there is room.
+// Expired entries are removed first, and if there are still too many
+// entries, the oldest ones that are not blocked. Blocked entries are
+// never removed, otherwise failing from new IPs would lift the blocks.
+func (b *Blocklist) evict(now time.Time) bool {
+	for ip, e := range b.entries {
+		if b.expired(e, now) {
+			delete(b.entries, ip)
+		}
+	}
+	entries := b.list()
+	for i := len(entries) - 1; i >= 0 && len(b.entries) >= b.limits.MaxEntries; i-- {
+		if now.Before(entries[i].BlockedUntil(b.limits)) {
+			continue
+		}
+		delete(b.entries, entries[i].IP)
+	}
+	return len(b.entries) < b.limits.MaxEntries
+}
+
+// Save persists the blocklist if it has changes.
+func (b *Blocklist) Save() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.dirty || b.path == "" {
+		return nil
+	}
+
+	data, err := yaml.Marshal(b.list())
+	if err != nil {
+		return fmt.Errorf("cannot encode blocklist: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(b.path), fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return fmt.Errorf("cannot create blocklist folder: %w", err)
+	}
+	err = os.WriteFile(b.path, data, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot save blocklist: %w", err)
+	}
+	b.dirty = false
+	return nil
+}
+
+// Run collects garbage and persists the blocklist periodically until
+// the given context is canceled, the blocklist is saved before return.
+func (b *Blocklist) Run(ctx context.Context) {
+	t := time.NewTicker(b.limits.GCInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := b.Save(); err != nil {
+				log.Println(err)
+			}
+			return
+		case now := <-t.C:
+			b.GC(now.UTC())
+			if err := b.Save(); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
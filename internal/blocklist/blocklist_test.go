@@ -0,0 +1,117 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package blocklist_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"changkun.de/x/midgard/internal/blocklist"
+)
+
+func TestBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.yml")
+	limits := blocklist.Limits{Attempts: 2, BlockTime: time.Minute}
+	b, err := blocklist.New(path, limits)
+	if err != nil {
+		t.Fatalf("cannot create blocklist: %v", err)
+	}
+
+	now := time.Now().UTC()
+	ip := "10.0.0.1"
+	for i := 0; i < 3; i++ {
+		if _, blocked := b.Blocked(ip, now); blocked {
+			t.Fatalf("blocked after %d failures", i)
+		}
+		b.Fail(ip, now)
+	}
+	until, blocked := b.Blocked(ip, now)
+	if !blocked || !until.Equal(now.Add(time.Minute)) {
+		t.Fatalf("not blocked after too many failures, until: %v", until)
+	}
+
+	// the block state must survive a restart
+	if err := b.Save(); err != nil {
+		t.Fatalf("cannot save blocklist: %v", err)
+	}
+	b, err = blocklist.New(path, limits)
+	if err != nil {
+		t.Fatalf("cannot reload blocklist: %v", err)
+	}
+	if _, blocked := b.Blocked(ip, now.Add(time.Second)); !blocked {
+		t.Fatalf("block is lost after restart")
+	}
+
+	// after the block expires, the next block lasts twice as long
+	later := now.Add(2 * time.Minute)
+	if _, blocked := b.Blocked(ip, later); blocked {
+		t.Fatalf("still blocked after the block expires")
+	}
+	for i := 0; i < 3; i++ {
+		b.Fail(ip, later)
+	}
+	until, _ = b.Blocked(ip, later)
+	if !until.Equal(later.Add(2 * time.Minute)) {
+		t.Fatalf("block time is not doubled, until: %v", until)
+	}
+
+	if !b.Clear(ip) {
+		t.Fatalf("cannot clear a blocked ip")
+	}
+	if _, blocked := b.Blocked(ip, later); blocked {
+		t.Fatalf("still blocked after clear")
+	}
+}
+
+func TestBlocklistGC(t *testing.T) {
+	b, err := blocklist.New("", blocklist.Limits{
+		Attempts:   1,
+		BlockTime:  time.Hour,
+		Forget:     time.Minute,
+		MaxEntries: 3,
+	})
+	if err != nil {
+		t.Fatalf("cannot create blocklist: %v", err)
+	}
+
+	now := time.Now().UTC()
+	b.Fail("blocked", now)
+	b.Fail("blocked", now)
+	b.Fail("failed", now)
+
+	b.GC(now.Add(2 * time.Minute))
+	entries := b.List()
+	if len(entries) != 1 || entries[0].IP != "blocked" {
+		t.Fatalf("unexpected entries after gc: %v", entries)
+	}
+
+	// the number of entries is bounded
+	for i := 0; i < 10; i++ {
+		b.Fail(fmt.Sprintf("10.0.0.%d", i), now.Add(time.Duration(i)*time.Second))
+	}
+	entries = b.List()
+	if len(entries) != 3 || entries[0].IP != "10.0.0.9" {
+		t.Fatalf("unexpected entries after eviction: %v", entries)
+	}
+	if _, blocked := b.Blocked("blocked", now.Add(time.Minute)); !blocked {
+		t.Fatalf("eviction lifts an active block")
+	}
+
+	// blocked entries are never evicted, new IPs are not tracked then
+	b.Fail("10.0.0.8", now.Add(10*time.Second))
+	b.Fail("10.0.0.9", now.Add(10*time.Second))
+	b.Fail("10.0.1.0", now.Add(11*time.Second))
+	entries = b.List()
+	if len(entries) != 3 || entries[0].IP == "10.0.1.0" {
+		t.Fatalf("unexpected entries after eviction: %v", entries)
+	}
+	for _, ip := range []string{"blocked", "10.0.0.8", "10.0.0.9"} {
+		if _, blocked := b.Blocked(ip, now.Add(time.Minute)); !blocked {
+			t.Fatalf("eviction lifts the block of %s", ip)
+		}
+	}
+}
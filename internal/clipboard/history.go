@@ -0,0 +1,452 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package clipboard
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"changkun.de/x/midgard/internal/storage"
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// HistoryEntry is a single change of a universal clipboard.
+type HistoryEntry struct {
+	ID     uint64     // incremental, starts from 1
+	Time   time.Time  // when the change was received
+	Type   types.MIME // MIME type of the data
+	Data   []byte     // raw clipboard data
+	Source string     // daemon ID or client IP that produced the change
+	Hash   string     // hex encoded sha256 of the data
+
+	// Alternatives are other representations of the same content.
+	Alternatives []types.Representation
+}
+
+// Representations returns all representations of the entry, the
+// primary representation comes first.
+func (e HistoryEntry) Representations() []types.Representation {
+	return append([]types.Representation{{Type: e.Type, Data: e.Data}}, e.Alternatives...)
+}
+
+// HistoryQuery filters the entries returned by History.List.
+type HistoryQuery struct {
+	Offset int       // number of entries to skip, newest first
+	Limit  int       // maximum number of entries, zero means no limit
+	Since  time.Time // inclusive lower time bound, ignored if zero
+	Until  time.Time // exclusive upper time bound, ignored if zero
+}
+
+// History is a persistent clipboard history. All changes are appended
+// to daily log files, organized as /<year>/<month>/<day>.log in its
+// storage. The entries are indexed on first access, only their metadata
+// are kept in memory and their data are read from the logs on demand.
+type History struct {
+	store storage.Storage
+
+	once    sync.Once
+	mu      sync.Mutex
+	entries []*historyEntry // ordered by time
+	records map[string]int  // number of records of each log
+}
+
+// historyEntry is an entry of the history in memory, its data are left
+// out unless the entry could not be persisted.
+type historyEntry struct {
+	HistoryEntry
+	log    string // key of the log of the entry, empty if not persisted
+	record int    // index of the record of the entry in its log
+}
+
+// NewHistory returns a clipboard history persisted in the given folder.
+func NewHistory(dir string) *History {
+	return NewHistoryIn(storage.NewFS(dir))
+}
+
+// NewHistoryIn returns a clipboard history persisted in the given storage.
+func NewHistoryIn(s storage.Storage) *History {
+	return &History{store: s}
+}
+
+// record is the on-disk format of a history entry.
+//
+// The time, type and data fields are compatible with the earlier log
+// format where only plain text data were persisted.
+type record struct {
+	Time         time.Time  `yaml:"time"`
+	Type         types.MIME `yaml:"type"`
+	Data         string     `yaml:"data"`
+	Encoding     string     `yaml:"encoding,omitempty"`
+	Source       string     `yaml:"source,omitempty"`
+	Hash         string     `yaml:"hash,omitempty"`
+	Alternatives []record   `yaml:"alternatives,omitempty"`
+}
+
+const encodingBase64 = "base64"
+
+func newRecord(r types.Representation) record {
+	if r.Type.IsText() {
+		return record{Type: r.Type, Data: utils.BytesToString(r.Data)}
+	}
+	return record{
+		Type:     r.Type,
+		Data:     base64.StdEncoding.EncodeToString(r.Data),
+		Encoding: encodingBase64,
+	}
+}
+
+func (r record) bytes() ([]byte, error) {
+	if r.Encoding == encodingBase64 {
+		return base64.StdEncoding.DecodeString(r.Data)
+	}
+	return utils.StringToBytes(r.Data), nil
+}
+
+func (h *History) load() {
+	h.once.Do(func() {
+		h.records = map[string]int{}
+		// Only /<year>/<month>/<day>.log are history logs, other
+		// folders may hold the history of other clipboards.
+		for _, f := range h.logs() {
+			err := h.scan(f, func(i int, r record) error {
+				h.records[f] = i + 1
+				if r.lost() {
+					return nil
+				}
+				e, err := r.entry()
+				if err != nil {
+					log.Printf("skip corrupted record %d of clipboard history %s: %v", i, f, err)
+					return nil
+				}
+				e.Data, e.Alternatives = nil, nil
+				h.entries = append(h.entries, &historyEntry{HistoryEntry: e, log: f, record: i})
+				return nil
+			})
+			if err != nil {
+				log.Printf("cannot load clipboard history from %s: %v", f, err)
+			}
+		}
+		sort.SliceStable(h.entries, func(i, j int) bool {
+			return h.entries[i].Time.Before(h.entries[j].Time)
+		})
+		for i := range h.entries {
+			h.entries[i].ID = uint64(i + 1)
+		}
+	})
+}
+
+// logs returns the keys of all log files of the history.
+func (h *History) logs() []string {
+	var keys []string
+	years, _ := h.store.List("/")
+	for _, y := range years {
+		if !y.Dir {
+			continue
+		}
+		months, _ := h.store.List(y.Key)
+		for _, m := range months {
+			if !m.Dir {
+				continue
+			}
+			days, _ := h.store.List(m.Key)
+			for _, d := range days {
+				if !d.Dir && strings.HasSuffix(d.Key, ".log") {
+					keys = append(keys, d.Key)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// scan calls fn with each record of the given log and its index in
+// the log, until fn returns an error. Records are the documents of the
+// log, separated by "---" lines. A record that cannot be decoded is
+// logged and skipped, the following records are still scanned.
+func (h *History) scan(key string, fn func(i int, r record) error) error {
+	f, _, err := h.store.Open(key)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var (
+		br  = bufio.NewReader(f)
+		doc []byte
+		i   int
+	)
+	for {
+		line, err := br.ReadBytes('\n')
+		eof := errors.Is(err, io.EOF)
+		if err != nil && !eof {
+			return err
+		}
+		sep := bytes.Equal(bytes.TrimRight(line, "\r\n"), []byte("---"))
+		if !sep {
+			doc = append(doc, line...)
+		}
+		if (sep || eof) && len(bytes.TrimSpace(doc)) > 0 {
+			var r record
+			if err := yaml.Unmarshal(doc, &r); err != nil {
+				log.Printf("skip corrupted record %d of clipboard history %s: %v", i, key, err)
+			} else if err := fn(i, r); err != nil {
+				return err
+			}
+			i++
+		}
+		if eof {
+			return nil
+		}
+		if sep {
+			doc = doc[:0]
+		}
+	}
+}
+
+// lost reports whether the data of the record are lost. Earlier logs
+// only kept the MIME type of non-text data, there is nothing we can
+// recover.
+func (r record) lost() bool {
+	return r.Encoding != encodingBase64 && !r.Type.IsText() && r.Hash == ""
+}
+
+// entry returns the history entry of the record, without ID.
+func (r record) entry() (HistoryEntry, error) {
+	data, err := r.bytes()
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	if r.Hash == "" {
+		r.Hash = hash(data)
+	}
+	e := HistoryEntry{
+		Time:   r.Time,
+		Type:   r.Type,
+		Data:   data,
+		Source: r.Source,
+		Hash:   r.Hash,
+	}
+	for _, alt := range r.Alternatives {
+		buf, err := alt.bytes()
+		if err != nil {
+			return HistoryEntry{}, err
+		}
+		e.Alternatives = append(e.Alternatives, types.Representation{Type: alt.Type, Data: buf})
+	}
+	return e, nil
+}
+
+// errScanned stops scanning a log once the wanted records are read.
+var errScanned = errors.New("records are scanned")
+
+// read returns the given entries with their data, the data of each log
+// are read at most once.
+func (h *History) read(entries ...*historyEntry) ([]HistoryEntry, error) {
+	out := make([]HistoryEntry, len(entries))
+	logs := map[string]map[int]int{} // log -> record -> index of out
+	for i, e := range entries {
+		out[i] = e.HistoryEntry
+		if e.log == "" {
+			continue
+		}
+		if logs[e.log] == nil {
+			logs[e.log] = map[int]int{}
+		}
+		logs[e.log][e.record] = i
+	}
+
+	for key, records := range logs {
+		last := 0
+		for n := range records {
+			last = max(last, n)
+		}
+		err := h.scan(key, func(n int, r record) error {
+			i, ok := records[n]
+			if ok {
+				e, err := r.entry()
+				if err != nil {
+					return err
+				}
+				if e.Hash != out[i].Hash {
+					return fmt.Errorf("record %d does not match entry %d", n, out[i].ID)
+				}
+				out[i].Data, out[i].Alternatives = e.Data, e.Alternatives
+				delete(records, n)
+			}
+			if n >= last {
+				return errScanned
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errScanned) {
+			return out, fmt.Errorf("cannot read clipboard history from %s: %w", key, err)
+		}
+		if len(records) > 0 {
+			return out, fmt.Errorf("cannot read clipboard history from %s: %d records are missing", key, len(records))
+		}
+	}
+	return out, nil
+}
+
+// Append records a new clipboard change to the history. The first given
+// representation is the primary one, the hash of an entry is computed
+// from the data of its primary representation.
+func (h *History) Append(source string, reps ...types.Representation) (HistoryEntry, error) {
+	if len(reps) == 0 {
+		return HistoryEntry{}, errors.New("no clipboard data to append")
+	}
+
+	h.load()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e := HistoryEntry{
+		ID:           uint64(len(h.entries) + 1),
+		Time:         time.Now().UTC(),
+		Type:         reps[0].Type,
+		Data:         reps[0].Data,
+		Source:       source,
+		Hash:         hash(reps[0].Data),
+		Alternatives: reps[1:],
+	}
+	key, err := h.persist(e)
+	if err != nil {
+		// keep the data in memory, there is no log to read them from.
+		h.entries = append(h.entries, &historyEntry{HistoryEntry: e})
+		return e, err
+	}
+	meta := e
+	meta.Data, meta.Alternatives = nil, nil
+	h.entries = append(h.entries, &historyEntry{HistoryEntry: meta, log: key, record: h.records[key]})
+	h.records[key]++
+	return e, nil
+}
+
+// persist appends the entry to its daily log and returns the key of
+// the log.
+func (h *History) persist(e HistoryEntry) (string, error) {
+	r := newRecord(types.Representation{Type: e.Type, Data: e.Data})
+	r.Time = e.Time
+	r.Source = e.Source
+	r.Hash = e.Hash
+	for _, alt := range e.Alternatives {
+		r.Alternatives = append(r.Alternatives, newRecord(alt))
+	}
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("cannot persist the given clipboard data: %w", err)
+	}
+
+	key := path.Join("/", strconv.Itoa(e.Time.Year()),
+		strconv.Itoa(int(e.Time.Month())), fmt.Sprintf("%d.log", e.Time.Day()))
+	all := utils.StringToBytes("---\n")
+	all = append(all, data...)
+	if err := h.store.Append(key, all); err != nil {
+		return "", fmt.Errorf("cannot write clipboard data to log: %w", err)
+	}
+	return key, nil
+}
+
+// List returns the entries that match the given query, newest first,
+// and the total number of matched entries regardless of pagination.
+func (h *History) List(q HistoryQuery) ([]HistoryEntry, int) {
+	h.load()
+	h.mu.Lock()
+	var (
+		matched []*historyEntry
+		total   int
+	)
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		e := h.entries[i]
+		if !q.Since.IsZero() && e.Time.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+			continue
+		}
+		total++
+		if total <= q.Offset {
+			continue
+		}
+		if q.Limit > 0 && len(matched) >= q.Limit {
+			continue
+		}
+		matched = append(matched, e)
+	}
+	h.mu.Unlock()
+
+	// entries are never modified once appended, their data are read
+	// without holding the lock.
+	entries, err := h.read(matched...)
+	if err != nil {
+		log.Println(err)
+	}
+	return entries, total
+}
+
+// Get returns the entry of the given ID.
+func (h *History) Get(id uint64) (HistoryEntry, bool) {
+	h.load()
+	h.mu.Lock()
+	if id == 0 || id > uint64(len(h.entries)) {
+		h.mu.Unlock()
+		return HistoryEntry{}, false
+	}
+	e := h.entries[id-1]
+	h.mu.Unlock()
+	return h.readOne(e)
+}
+
+// Last returns the latest entry of the history.
+func (h *History) Last() (HistoryEntry, bool) {
+	h.load()
+	h.mu.Lock()
+	if len(h.entries) == 0 {
+		h.mu.Unlock()
+		return HistoryEntry{}, false
+	}
+	e := h.entries[len(h.entries)-1]
+	h.mu.Unlock()
+	return h.readOne(e)
+}
+
+// readOne returns the given entry with its data, and false if its data
+// cannot be read.
+func (h *History) readOne(e *historyEntry) (HistoryEntry, bool) {
+	entries, err := h.read(e)
+	if err != nil {
+		log.Println(err)
+		return HistoryEntry{}, false
+	}
+	return entries[0], true
+}
+
+// Len returns the number of entries in the history.
+func (h *History) Len() int {
+	h.load()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entries)
+}
+
+func hash(buf []byte) string {
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,204 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package clipboard_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"changkun.de/x/midgard/internal/clipboard"
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/utils"
+)
+
+func TestHistoryPersist(t *testing.T) {
+	dir := t.TempDir()
+
+	img, err := os.ReadFile("testdata/gold.png")
+	if err != nil {
+		t.Fatalf("failed to read gold file, err: %v", err)
+	}
+
+	h := clipboard.NewHistory(dir)
+	if _, err := h.Append("daemon-a", types.Representation{Type: types.MIMEPlainText, Data: utils.StringToBytes("hello")}); err != nil {
+		t.Fatalf("failed to append text: %v", err)
+	}
+	if _, err := h.Append("daemon-b", types.Representation{Type: types.MIMEImagePNG, Data: img}); err != nil {
+		t.Fatalf("failed to append image: %v", err)
+	}
+
+	// a new history must load everything back from the disk.
+	h = clipboard.NewHistory(dir)
+	entries, total := h.List(clipboard.HistoryQuery{})
+	if total != 2 || len(entries) != 2 {
+		t.Fatalf("unexpected number of entries, want 2, got %d", total)
+	}
+
+	// newest first
+	if entries[0].ID != 2 || entries[0].Type != types.MIMEImagePNG ||
+		entries[0].Source != "daemon-b" || !bytes.Equal(entries[0].Data, img) {
+		t.Fatalf("image entry is not persisted correctly: %v, %v", entries[0].Type, entries[0].Source)
+	}
+	if entries[1].ID != 1 || entries[1].Type != types.MIMEPlainText ||
+		entries[1].Source != "daemon-a" || utils.BytesToString(entries[1].Data) != "hello" {
+		t.Fatalf("text entry is not persisted correctly: %v, %v", entries[1].Type, entries[1].Source)
+	}
+	if entries[0].Hash == "" || entries[0].Hash == entries[1].Hash {
+		t.Fatalf("unexpected content hash: %v, %v", entries[0].Hash, entries[1].Hash)
+	}
+
+	e, ok := h.Get(1)
+	if !ok || e.Hash != entries[1].Hash {
+		t.Fatalf("failed to get entry by id")
+	}
+	if _, ok := h.Get(3); ok {
+		t.Fatalf("get a non-existing entry")
+	}
+}
+
+func TestHistoryAppendLoaded(t *testing.T) {
+	dir := t.TempDir()
+	text := func(s string) types.Representation {
+		return types.Representation{Type: types.MIMEPlainText, Data: utils.StringToBytes(s)}
+	}
+	if _, err := clipboard.NewHistory(dir).Append("", text("a")); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	// the data of entries appended to a loaded log are read from the
+	// records after the loaded ones.
+	h := clipboard.NewHistory(dir)
+	for _, s := range []string{"b", "c"} {
+		if _, err := h.Append("", text(s)); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	for i, want := range []string{"a", "b", "c"} {
+		e, ok := h.Get(uint64(i + 1))
+		if !ok || string(e.Data) != want {
+			t.Fatalf("entry %d is %q, %v, want %q", i+1, e.Data, ok, want)
+		}
+	}
+	entries, _ := h.List(clipboard.HistoryQuery{})
+	if len(entries) != 3 || string(entries[0].Data) != "c" || string(entries[2].Data) != "a" {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+}
+
+func TestHistoryAlternatives(t *testing.T) {
+	dir := t.TempDir()
+	reps := []types.Representation{
+		{Type: types.MIMEPlainText, Data: utils.StringToBytes("hello")},
+		{Type: types.MIMEHTML, Data: utils.StringToBytes("<b>hello</b>")},
+	}
+	if _, err := clipboard.NewHistory(dir).Append("daemon", reps...); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+
+	e, ok := clipboard.NewHistory(dir).Last()
+	if !ok {
+		t.Fatalf("failed to load the entry")
+	}
+	got := e.Representations()
+	if len(got) != 2 || got[1].Type != types.MIMEHTML || !bytes.Equal(got[1].Data, reps[1].Data) {
+		t.Fatalf("alternatives are not persisted: %v", got)
+	}
+}
+
+func TestHistoryLegacyLog(t *testing.T) {
+	dir := t.TempDir()
+	legacy := `---
+time: 2021-01-02T03:04:05Z
+type: text
+data: legacy
+---
+time: 2021-01-02T03:04:06Z
+type: image/png
+data: image/png
+`
+	err := os.MkdirAll(filepath.Join(dir, "2021", "1"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "2021", "1", "2.log"), utils.StringToBytes(legacy), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, total := clipboard.NewHistory(dir).List(clipboard.HistoryQuery{})
+	if total != 1 {
+		t.Fatalf("unrecoverable legacy entries must be skipped, got %d entries", total)
+	}
+	if utils.BytesToString(entries[0].Data) != "legacy" || entries[0].Hash == "" {
+		t.Fatalf("failed to load legacy entry: %s", entries[0].Data)
+	}
+}
+
+func TestHistoryCorruptedLog(t *testing.T) {
+	dir := t.TempDir()
+	corrupted := `---
+time: 2021-01-02T03:04:05Z
+type: text
+data: first
+---
+time: [broken
+---
+time: 2021-01-02T03:04:06Z
+type: image/png
+data: '%%%'
+encoding: base64
+---
+time: 2021-01-02T03:04:07Z
+type: text
+data: |-
+    last
+    ---
+`
+	err := os.MkdirAll(filepath.Join(dir, "2021", "1"), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "2021", "1", "2.log"), utils.StringToBytes(corrupted), os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, total := clipboard.NewHistory(dir).List(clipboard.HistoryQuery{})
+	if total != 2 || len(entries) != 2 {
+		t.Fatalf("corrupted records must be skipped, got %d entries", total)
+	}
+	if utils.BytesToString(entries[0].Data) != "last\n---" || utils.BytesToString(entries[1].Data) != "first" {
+		t.Fatalf("failed to load the records after a corrupted one: %q, %q", entries[0].Data, entries[1].Data)
+	}
+}
+
+func TestHistoryQuery(t *testing.T) {
+	h := clipboard.NewHistory(t.TempDir())
+	start := time.Now().UTC()
+	for _, s := range []string{"a", "b", "c", "d", "e"} {
+		h.Append("", types.Representation{Type: types.MIMEPlainText, Data: utils.StringToBytes(s)})
+	}
+	end := time.Now().UTC().Add(time.Second)
+
+	entries, total := h.List(clipboard.HistoryQuery{Offset: 1, Limit: 2})
+	if total != 5 || len(entries) != 2 {
+		t.Fatalf("unexpected pagination, total: %d, len: %d", total, len(entries))
+	}
+	if string(entries[0].Data) != "d" || string(entries[1].Data) != "c" {
+		t.Fatalf("unexpected page: %s, %s", entries[0].Data, entries[1].Data)
+	}
+
+	_, total = h.List(clipboard.HistoryQuery{Since: start, Until: end})
+	if total != 5 {
+		t.Fatalf("time range does not match all entries, got %d", total)
+	}
+	_, total = h.List(clipboard.HistoryQuery{Since: end})
+	if total != 0 {
+		t.Fatalf("time range matches future entries, got %d", total)
+	}
+}
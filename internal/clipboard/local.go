@@ -7,6 +7,10 @@ package clipboard
 import (
 	"bytes"
 	"context"
+	"image"
+	_ "image/gif"  // for decoding gif images
+	_ "image/jpeg" // for decoding jpeg images
+	"image/png"
 	"sync"
 
 	"changkun.de/x/midgard/internal/types"
@@ -17,6 +21,12 @@ import (
 // for local purpose
 type LocalClipboard interface {
 	Clipboard
+	// ReadAll reads all representations that are currently available
+	// in the local clipboard.
+	ReadAll() []types.Representation
+	// WriteAll writes all supported representations of the same content
+	// to the clipboard, and returns false if none of them is supported.
+	WriteAll(reps ...types.Representation) bool
 	// Watch watches a given type of data from local clipboard and
 	// send the data back through a provided channel.
 	Watch(ctx context.Context, dt types.MIME) <-chan []byte
@@ -51,7 +61,42 @@ func (lc *local) Read() (t types.MIME, buf []byte) {
 	return
 }
 
-// Write writes the given buffer to the clipboard.
+// ReadAll reads all representations in the clipboard.
+func (lc *local) ReadAll() []types.Representation {
+	lc.Lock()
+	defer lc.Unlock()
+
+	var reps []types.Representation
+	if buf := clipboard.Read(clipboard.FmtText); buf != nil {
+		reps = append(reps, types.Representation{Type: types.MIMEPlainText, Data: buf})
+	}
+	if buf := clipboard.Read(clipboard.FmtImage); buf != nil {
+		reps = append(reps, types.Representation{Type: types.MIMEImagePNG, Data: buf})
+	}
+	return reps
+}
+
+// WriteAll writes all given representations that the clipboard supports,
+// and reports whether any of them is written.
+//
+// The underlying clipboard package replaces the whole clipboard content
+// on each write, hence the representations are written in the reverse
+// order, and the first one is kept if the OS clipboard cannot hold more.
+func (lc *local) WriteAll(reps ...types.Representation) bool {
+	written := false
+	for i := len(reps) - 1; i >= 0; i-- {
+		if lc.Write(reps[i].Type, reps[i].Data) {
+			written = true
+		}
+	}
+	return written
+}
+
+// Write writes the given buffer to the clipboard. It returns false if
+// the given MIME type cannot be rendered by the OS clipboard.
+//
+// Images that are not PNG encoded, such as JPEG and GIF, are converted
+// to PNG before writing to the clipboard, see Writable.
 func (lc *local) Write(t types.MIME, buf []byte) bool {
 	lc.Lock()
 	defer lc.Unlock()
@@ -60,17 +105,57 @@ func (lc *local) Write(t types.MIME, buf []byte) bool {
 	if bytes.Equal(lc.buf, buf) {
 		return true // but we recognize it as a success write
 	}
+
+	f, buf, ok := writable(t, buf)
+	if !ok {
+		return false
+	}
 	lc.buf = buf
 	lc.typ = t
-	switch t {
-	case types.MIMEPlainText:
-		clipboard.Write(clipboard.FmtText, buf)
-	case types.MIMEImagePNG:
-		clipboard.Write(clipboard.FmtImage, buf)
-	}
+	clipboard.Write(f, buf)
 	return true
 }
 
+// Writable returns the given representations that the local clipboard
+// supports as they are written to it, e.g. JPEG images become PNG.
+func Writable(reps ...types.Representation) []types.Representation {
+	var out []types.Representation
+	for _, r := range reps {
+		f, buf, ok := writable(r.Type, r.Data)
+		if !ok {
+			continue
+		}
+		t := types.MIMEPlainText
+		if f == clipboard.FmtImage {
+			t = types.MIMEImagePNG
+		}
+		out = append(out, types.Representation{Type: t, Data: buf})
+	}
+	return out
+}
+
+// writable returns the format of the OS clipboard and the data of the
+// given MIME type to write, and false if it cannot be written.
+func writable(t types.MIME, buf []byte) (clipboard.Format, []byte, bool) {
+	switch {
+	case t == types.MIMEPlainText:
+		return clipboard.FmtText, buf, true
+	case t == types.MIMEImagePNG:
+		return clipboard.FmtImage, buf, true
+	case t.IsImage():
+		img, _, err := image.Decode(bytes.NewReader(buf))
+		if err != nil {
+			return 0, nil, false
+		}
+		var b bytes.Buffer
+		if err := png.Encode(&b, img); err != nil {
+			return 0, nil, false
+		}
+		return clipboard.FmtImage, b.Bytes(), true
+	}
+	return 0, nil, false
+}
+
 // Watch watches clipboard changes and closes the dataCh channel if
 // the the watch context is canceled.
 func (lc *local) Watch(ctx context.Context, dt types.MIME) <-chan []byte {
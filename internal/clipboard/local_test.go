@@ -7,6 +7,8 @@ package clipboard_test
 import (
 	"bytes"
 	"context"
+	"image"
+	"image/jpeg"
 	"image/png"
 	"os"
 	"reflect"
@@ -118,3 +120,21 @@ func TestLocalClipboardWatch(t *testing.T) {
 		}
 	}
 }
+
+func TestWritable(t *testing.T) {
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	reps := clipboard.Writable(
+		types.Representation{Type: types.MIMEPlainText, Data: utils.StringToBytes("hello")},
+		types.Representation{Type: types.MIMEHTML, Data: utils.StringToBytes("<b>hello</b>")},
+		types.Representation{Type: types.MIMEImageJPEG, Data: jpg.Bytes()},
+	)
+	if len(reps) != 2 || reps[0].Type != types.MIMEPlainText || reps[1].Type != types.MIMEImagePNG {
+		t.Fatalf("unexpected writable representations: %v", reps)
+	}
+	if _, err := png.Decode(bytes.NewReader(reps[1].Data)); err != nil {
+		t.Fatalf("jpeg image is not converted to png: %v", err)
+	}
+}
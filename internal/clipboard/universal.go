@@ -6,16 +6,11 @@ package clipboard
 
 import (
 	"bytes"
-	"fmt"
-	"io/fs"
 	"log"
-	"os"
 	"sync"
-	"time"
 
+	"changkun.de/x/midgard/internal/storage"
 	"changkun.de/x/midgard/internal/types"
-	"changkun.de/x/midgard/internal/utils"
-	"gopkg.in/yaml.v3"
 )
 
 // Clipboard is an interface that defines the operations of a clipboard
@@ -32,103 +27,128 @@ type Clipboard interface {
 type UniversalClipboard interface {
 	Clipboard
 	// ReadAs reads the clipboard as a given MIME type and return
-	// the raw bytes if the type matches or nil if it does not.
+	// the raw bytes if any representation of the clipboard matches
+	// the type or nil if none of them does.
 	// This method is generally faster than the Clipboard.Read because
 	// it avoids data copy if the MIME type does not match.
 	ReadAs(t types.MIME) []byte
+	// ReadAll reads all representations of the clipboard, the primary
+	// representation that is returned by Read comes first.
+	ReadAll() []types.Representation
+	// Put is like Write but accepts multiple representations of the
+	// same content, and records the source of the data, e.g. a daemon
+	// ID, in the clipboard history. The first representation is the
+	// primary one.
+	Put(source string, reps ...types.Representation) bool
+	// History returns the change history of the clipboard.
+	History() *History
+	// Restore restores the clipboard to the latest entry of its
+	// history and returns true if there was an entry to restore.
+	Restore() bool
 }
 
-// Universal is the Midgard's universal clipboard, it keeps the data in
-// memory and logs its change history to the data store of midgard.
-//
-// It holds a global shared storage that can be edited/fetched at anytime.
-var Universal UniversalClipboard = &universal{
-	typ: types.MIMEPlainText,
-	buf: []byte{},
+// NewUniversal creates a new universal clipboard that persists its
+// history in the given folder.
+func NewUniversal(dir string) UniversalClipboard {
+	return NewUniversalIn(storage.NewFS(dir))
+}
+
+// NewUniversalIn creates a new universal clipboard that persists its
+// history in the given storage.
+func NewUniversalIn(s storage.Storage) UniversalClipboard {
+	return &universal{
+		reps:    []types.Representation{{Type: types.MIMEPlainText, Data: []byte{}}},
+		history: NewHistoryIn(s),
+	}
 }
 
 type universal struct {
 	sync.Mutex
-	typ types.MIME
-	buf []byte
+	reps    []types.Representation // never empty, primary comes first
+	history *History
 }
 
 func (uc *universal) Read() (types.MIME, []byte) {
 	uc.Lock()
 	defer uc.Unlock()
-	buf := make([]byte, len(uc.buf))
-	copy(buf, uc.buf)
-	return uc.typ, buf
+	buf := make([]byte, len(uc.reps[0].Data))
+	copy(buf, uc.reps[0].Data)
+	return uc.reps[0].Type, buf
 }
 
 func (uc *universal) ReadAs(t types.MIME) []byte {
 	uc.Lock()
 	defer uc.Unlock()
-	if t != uc.typ {
-		return nil
+	for _, r := range uc.reps {
+		if r.Type != t {
+			continue
+		}
+		buf := make([]byte, len(r.Data))
+		copy(buf, r.Data)
+		return buf
 	}
+	return nil
+}
 
-	buf := make([]byte, len(uc.buf))
-	copy(buf, uc.buf)
-	return buf
+func (uc *universal) ReadAll() []types.Representation {
+	uc.Lock()
+	defer uc.Unlock()
+	reps := make([]types.Representation, len(uc.reps))
+	for i, r := range uc.reps {
+		reps[i].Type = r.Type
+		reps[i].Data = make([]byte, len(r.Data))
+		copy(reps[i].Data, r.Data)
+	}
+	return reps
 }
 
 func (uc *universal) Write(t types.MIME, buf []byte) bool {
+	return uc.Put("", types.Representation{Type: t, Data: buf})
+}
+
+func (uc *universal) Put(source string, reps ...types.Representation) bool {
+	if len(reps) == 0 {
+		return false
+	}
+
 	uc.Lock()
 	defer uc.Unlock()
-	if uc.typ == t && bytes.Equal(uc.buf, buf) {
+	if equal(uc.reps, reps) {
 		return false
 	}
 
-	uc.log(t, buf)
+	if _, err := uc.history.Append(source, reps...); err != nil {
+		log.Println(err)
+	}
 
-	uc.typ = t
-	uc.buf = buf
+	uc.reps = reps
 	return true
 }
 
-func (uc *universal) log(t types.MIME, buf []byte) {
-	if t != types.MIMEPlainText {
-		buf = utils.StringToBytes(string(t))
-	}
-
-	date := time.Now().UTC()
-	r := struct {
-		Time time.Time
-		Type types.MIME
-		Data string
-	}{
-		Time: date,
-		Type: t,
-		Data: utils.BytesToString(buf),
+func equal(a, b []types.Representation) bool {
+	if len(a) != len(b) {
+		return false
 	}
-	data, err := yaml.Marshal(r)
-	if err != nil {
-		log.Println("cannot persist the given clipboard data:", err)
-		return
-	}
-
-	logdir := "./data/logs/clipboard"
-	fpath := fmt.Sprintf("%s/%d/%d", logdir, date.Year(), date.Month())
-	err = os.MkdirAll(fpath, fs.ModeDir|fs.ModePerm)
-	if err != nil {
-		log.Println("cannot create log folder:", err)
-		return
+	for i := range a {
+		if a[i].Type != b[i].Type || !bytes.Equal(a[i].Data, b[i].Data) {
+			return false
+		}
 	}
+	return true
+}
 
-	f, err := os.OpenFile(fmt.Sprintf("%s/%d.log", fpath, date.Day()),
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY, fs.ModePerm)
-	if err != nil {
-		log.Println("cannot open or create clipboard log file:", err)
-		return
-	}
-	defer f.Close()
+func (uc *universal) History() *History {
+	return uc.history
+}
 
-	all := utils.StringToBytes("---\n")
-	all = append(all, data...)
-	if _, err := f.Write(all); err != nil {
-		log.Println("cannot write clipboard data to log:", err)
-		return
+func (uc *universal) Restore() bool {
+	e, ok := uc.history.Last()
+	if !ok {
+		return false
 	}
 
+	uc.Lock()
+	defer uc.Unlock()
+	uc.reps = e.Representations()
+	return true
 }
@@ -14,20 +14,21 @@ import (
 )
 
 func TestUniversalClipboard(t *testing.T) {
+	uc := clipboard.NewUniversal(t.TempDir())
 	buf := utils.StringToBytes("hello")
-	clipboard.Universal.Write(types.MIMEPlainText, buf)
+	uc.Write(types.MIMEPlainText, buf)
 
-	got := clipboard.Universal.ReadAs(types.MIMEPlainText)
+	got := uc.ReadAs(types.MIMEPlainText)
 	if !bytes.Equal(buf, got) {
 		t.Fatalf("failed to put data into ub.")
 	}
 
-	got = clipboard.Universal.ReadAs(types.MIMEImagePNG)
+	got = uc.ReadAs(types.MIMEImagePNG)
 	if bytes.Equal(buf, got) {
 		t.Fatalf("unexpected read from ub, want blank, got %v", utils.BytesToString(got))
 	}
 
-	tt, got := clipboard.Universal.Read()
+	tt, got := uc.Read()
 
 	if tt != types.MIMEPlainText {
 		t.Fatalf("incorrect data type")
@@ -38,3 +39,52 @@ func TestUniversalClipboard(t *testing.T) {
 
 	t.Log(utils.BytesToString(buf))
 }
+
+func TestUniversalClipboardAlternatives(t *testing.T) {
+	uc := clipboard.NewUniversal(t.TempDir())
+	html := utils.StringToBytes("<i>hello</i>")
+	updated := uc.Put("daemon",
+		types.Representation{Type: types.MIMEPlainText, Data: utils.StringToBytes("hello")},
+		types.Representation{Type: types.MIMEHTML, Data: html},
+	)
+	if !updated {
+		t.Fatalf("failed to put data into ub.")
+	}
+
+	tt, _ := uc.Read()
+	if tt != types.MIMEPlainText {
+		t.Fatalf("incorrect primary data type: %v", tt)
+	}
+	if got := uc.ReadAs(types.MIMEHTML); !bytes.Equal(got, html) {
+		t.Fatalf("failed to read alternative representation, got: %v", utils.BytesToString(got))
+	}
+	if n := len(uc.ReadAll()); n != 2 {
+		t.Fatalf("unexpected number of representations: %d", n)
+	}
+}
+
+func TestUniversalClipboardRestore(t *testing.T) {
+	dir := t.TempDir()
+
+	uc := clipboard.NewUniversal(dir)
+	if uc.Restore() {
+		t.Fatalf("restored from an empty history")
+	}
+	uc.Put("daemon", types.Representation{Type: types.MIMEPlainText, Data: utils.StringToBytes("first")})
+	uc.Put("daemon", types.Representation{Type: types.MIMEPlainText, Data: utils.StringToBytes("last")})
+
+	// simulates a server restart
+	uc = clipboard.NewUniversal(dir)
+	if !uc.Restore() {
+		t.Fatalf("failed to restore from history")
+	}
+	tt, got := uc.Read()
+	if tt != types.MIMEPlainText || utils.BytesToString(got) != "last" {
+		t.Fatalf("incorrect restored data, got: %v", utils.BytesToString(got))
+	}
+
+	// restoring must not create a new history entry
+	if n := uc.History().Len(); n != 2 {
+		t.Fatalf("unexpected number of history entries, want 2, got %d", n)
+	}
+}
@@ -36,22 +36,131 @@ type Server struct {
 	Mode  string `yaml:"mode"`
 	Store struct {
 		Prefix string `yaml:"prefix"`
+		// Backend is the storage of the data repository and the
+		// clipboard logs, either "fs" (default) or "s3".
+		Backend string `yaml:"backend"`
+		S3      struct {
+			Endpoint  string `yaml:"endpoint"`
+			Region    string `yaml:"region"`
+			Bucket    string `yaml:"bucket"`
+			AccessKey string `yaml:"access_key"`
+			SecretKey string `yaml:"secret_key"`
+		} `yaml:"s3"`
 		Backup struct {
-			Enable   bool   `yaml:"enable"`
-			Interval int    `yaml:"interval"`
+			Enable   bool `yaml:"enable"`
+			Interval int  `yaml:"interval"` // minutes between backups
+			// Strategy is either "git" (default) that pushes to Repo,
+			// or "tarball" that keeps the latest Keep snapshots in Dir.
+			Strategy string `yaml:"strategy"`
 			Repo     string `yaml:"repo"`
+			Dir      string `yaml:"dir"`
+			Keep     int    `yaml:"keep"`
+			// Logs also backs up the clipboard history of all users
+			// and channels, which is private and off by default.
+			Logs bool `yaml:"logs"`
+			// Notify posts to the webhook after the given number of
+			// consecutive failures, and after the backup recovers.
+			Notify struct {
+				Webhook string `yaml:"webhook"`
+				After   int    `yaml:"after"`
+			} `yaml:"notify"`
 		} `yaml:"backup"`
+		// Index enables the index pages of folders for authenticated
+		// users, see /midgard/api/v1/index/.
+		Index bool `yaml:"index"`
+		// Serve decides which top level folders of the repo are served
+		// publicly. Default is either "allow" (default) or "deny", and
+		// Deny precedes Allow. Internal files are never served.
+		Serve struct {
+			Default string   `yaml:"default"`
+			Allow   []string `yaml:"allow"`
+			Deny    []string `yaml:"deny"`
+		} `yaml:"serve"`
 	} `yaml:"store"`
 	Auth struct {
-		User string `yaml:"user"`
-		Pass string `yaml:"pass"`
+		User  string `yaml:"user"`
+		Pass  string `yaml:"pass"`
+		Users []User `yaml:"users"`
+		// Token is an API token that clients use instead of the user
+		// and password if it is not empty, see mg token.
+		Token string `yaml:"token"`
+		// Block limits the failed authentication attempts of an IP,
+		// zero values fall back to the defaults.
+		Block struct {
+			Attempts   int `yaml:"attempts"`    // allowed failures
+			BlockTime  int `yaml:"block_time"`  // seconds of the first block
+			MaxBlock   int `yaml:"max_block"`   // maximum seconds of a block
+			Forget     int `yaml:"forget"`      // seconds to forget a non-blocked ip
+			MaxEntries int `yaml:"max_entries"` // maximum number of tracked ips
+		} `yaml:"block"`
 	} `json:"auth"`
+	Channels []Channel `yaml:"channels"`
+	// Quota limits the storage of allocated resources and code2img
+	// codes, zero values are unlimited.
+	Quota struct {
+		MaxUpload int64            `yaml:"max_upload"` // bytes of a single upload
+		Total     Limit            `yaml:"total"`      // of all users
+		User      Limit            `yaml:"user"`       // of each user
+		Users     map[string]Limit `yaml:"users"`      // of specific users
+	} `yaml:"quota"`
+}
+
+// Limit is a storage limit.
+type Limit struct {
+	Bytes int64 `yaml:"bytes"`
+	Files int   `yaml:"files"`
+}
+
+// UserQuota returns the storage limit of the given user.
+func (s *Server) UserQuota(user string) Limit {
+	if l, ok := s.Quota.Users[user]; ok {
+		return l
+	}
+	return s.Quota.User
+}
+
+// User is a midgard user account.
+type User struct {
+	Name string `yaml:"user"`
+	Pass string `yaml:"pass"`
+}
+
+// Users returns all midgard users. The user of auth.user and auth.pass
+// comes first, followed by the additional users in auth.users.
+func (s *Server) Users() []User {
+	users := []User{{Name: s.Auth.User, Pass: s.Auth.Pass}}
+	return append(users, s.Auth.Users...)
+}
+
+// Channel is a named clipboard that is shared by its members.
+type Channel struct {
+	Name    string   `yaml:"name"`
+	Members []string `yaml:"members"`
+}
+
+// IsMember reports whether the given user is a member of the channel.
+func (c Channel) IsMember(user string) bool {
+	for _, m := range c.Members {
+		if m == user {
+			return true
+		}
+	}
+	return false
 }
 
 // Daemon is the midgard daemon configuration
 type Daemon struct {
 	Addr   string `yaml:"addr"`
 	Server string `yaml:"server"`
+	// Key is a shared key between daemons. If it is not empty, the
+	// clipboard data is end-to-end encrypted between daemons.
+	Key string `yaml:"key"`
+	// Channels are the shared channels that the daemon joins, changes
+	// of the channels are synced to the local clipboard.
+	Channels []string `yaml:"channels"`
+	// Publish is a channel that receives local clipboard changes
+	// instead of the private universal clipboard of the user.
+	Publish string `yaml:"publish"`
 }
 
 // S returns the midgard server configuration
@@ -28,3 +28,24 @@ func TestParseConfig(t *testing.T) {
 		t.Fatalf("read empty from config, field: %v", v.Type().Field(i).Name)
 	}
 }
+
+func TestServerUsers(t *testing.T) {
+	s := config.S()
+	users := s.Users()
+	if len(users) != len(s.Auth.Users)+1 {
+		t.Fatalf("unexpected number of users: %d", len(users))
+	}
+	if users[0].Name != s.Auth.User || users[0].Pass != s.Auth.Pass {
+		t.Fatalf("the configured auth.user must be the first user")
+	}
+}
+
+func TestChannelIsMember(t *testing.T) {
+	c := config.Channel{Name: "team", Members: []string{"alice", "bob"}}
+	if !c.IsMember("alice") || !c.IsMember("bob") {
+		t.Fatalf("members are not recognized")
+	}
+	if c.IsMember("eve") || c.IsMember("") {
+		t.Fatalf("non-members are recognized as members")
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package crypt implements the end-to-end encryption of clipboard data
+// between midgard daemons. The midgard server only relays and stores
+// the encrypted data and never sees the shared key.
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/utils"
+)
+
+// Errors
+var (
+	ErrEmptyKey     = errors.New("empty encryption key")
+	ErrNotEncrypted = errors.New("clipboard data is not encrypted")
+)
+
+// Cipher encrypts and decrypts clipboard data using AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New creates a cipher from the given shared key. The actual encryption
+// key is derived from the sha256 sum of the shared key, so the shared
+// key is expected to be long and random, e.g. openssl rand -base64 32.
+func New(key string) (*Cipher, error) {
+	if key == "" {
+		return nil, ErrEmptyKey
+	}
+	sum := sha256.Sum256(utils.StringToBytes(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// Seal encrypts the given clipboard data, including all its
+// representations, into an opaque clipboard data of MIMEEncrypted.
+func (c *Cipher) Seal(d types.ClipboardData) (types.ClipboardData, error) {
+	plain, err := json.Marshal(d)
+	if err != nil {
+		return types.ClipboardData{}, err
+	}
+
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plain)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return types.ClipboardData{}, fmt.Errorf("cannot generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, plain, []byte(types.MIMEEncrypted))
+	return types.NewClipboardData(types.MIMEEncrypted, sealed), nil
+}
+
+// Open decrypts the given clipboard data that was encrypted by Seal.
+func (c *Cipher) Open(d types.ClipboardData) (types.ClipboardData, error) {
+	if d.Type != types.MIMEEncrypted {
+		return types.ClipboardData{}, ErrNotEncrypted
+	}
+	sealed, err := d.Bytes()
+	if err != nil {
+		return types.ClipboardData{}, err
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return types.ClipboardData{}, errors.New("encrypted data is too short")
+	}
+
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, []byte(types.MIMEEncrypted))
+	if err != nil {
+		return types.ClipboardData{}, fmt.Errorf("cannot decrypt clipboard data, mismatched keys? %w", err)
+	}
+
+	var out types.ClipboardData
+	err = json.Unmarshal(plain, &out)
+	return out, err
+}
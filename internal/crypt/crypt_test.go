@@ -0,0 +1,68 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package crypt_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"changkun.de/x/midgard/internal/crypt"
+	"changkun.de/x/midgard/internal/types"
+)
+
+func TestSealOpen(t *testing.T) {
+	c, err := crypt.New("a shared secret")
+	if err != nil {
+		t.Fatalf("failed to create cipher: %v", err)
+	}
+
+	d := types.EncodeClipboardData(
+		types.Representation{Type: types.MIMEPlainText, Data: []byte("top secret")},
+		types.Representation{Type: types.MIMEImagePNG, Data: []byte{0x89, 'P', 'N', 'G'}},
+	)
+	sealed, err := c.Seal(d)
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if sealed.Type != types.MIMEEncrypted || len(sealed.Alternatives) != 0 {
+		t.Fatalf("sealed data leaks its type information: %v", sealed.Type)
+	}
+	if strings.Contains(sealed.Data, "top secret") {
+		t.Fatalf("sealed data contains plain text")
+	}
+
+	opened, err := c.Open(sealed)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	reps, err := opened.Decode()
+	if err != nil {
+		t.Fatalf("failed to decode opened data: %v", err)
+	}
+	if len(reps) != 2 || string(reps[0].Data) != "top secret" ||
+		!bytes.Equal(reps[1].Data, []byte{0x89, 'P', 'N', 'G'}) {
+		t.Fatalf("inconsistent data after decryption: %v", reps)
+	}
+}
+
+func TestOpenWrongKey(t *testing.T) {
+	c1, _ := crypt.New("key one")
+	c2, _ := crypt.New("key two")
+
+	sealed, err := c1.Seal(types.NewClipboardData(types.MIMEPlainText, []byte("hello")))
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if _, err := c2.Open(sealed); err == nil {
+		t.Fatalf("opened data with a wrong key")
+	}
+	if _, err := c1.Open(types.NewClipboardData(types.MIMEPlainText, []byte("hello"))); err != crypt.ErrNotEncrypted {
+		t.Fatalf("opened data that is not encrypted, err: %v", err)
+	}
+	if _, err := crypt.New(""); err != crypt.ErrEmptyKey {
+		t.Fatalf("created a cipher with an empty key")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package gallery indexes the code snippets of code2img for listing and
+// searching them without reading the repo on every request.
+package gallery
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Snippet is a code snippet of code2img.
+type Snippet struct {
+	ID   string // the name of the code file, e.g. 210301-120000
+	Time time.Time
+	Code string
+}
+
+// Index is an index of code snippets, newest first. The snippets are
+// loaded on first use, and are tracked by additions and removals
+// afterwards.
+type Index struct {
+	load func() ([]Snippet, error)
+
+	once     sync.Once
+	mu       sync.RWMutex
+	snippets []Snippet
+}
+
+// New creates an index of the snippets returned by the given load.
+func New(load func() ([]Snippet, error)) *Index {
+	return &Index{load: load}
+}
+
+func (x *Index) init() {
+	x.once.Do(func() {
+		snippets, err := x.load()
+		if err != nil {
+			// the index is incomplete, but new snippets are tracked.
+			log.Printf("cannot load code snippets: %v", err)
+		}
+		x.mu.Lock()
+		x.snippets = append(x.snippets, snippets...)
+		sort.SliceStable(x.snippets, func(i, j int) bool {
+			return x.snippets[i].Time.After(x.snippets[j].Time)
+		})
+		x.mu.Unlock()
+	})
+}
+
+// Add adds the snippet to the index, it replaces an existing snippet of
+// the same ID.
+func (x *Index) Add(s Snippet) {
+	x.init()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(s.ID)
+	i := sort.Search(len(x.snippets), func(i int) bool {
+		return !x.snippets[i].Time.After(s.Time)
+	})
+	x.snippets = append(x.snippets, Snippet{})
+	copy(x.snippets[i+1:], x.snippets[i:])
+	x.snippets[i] = s
+}
+
+// Remove removes the snippet of the given ID, and reports whether the
+// snippet existed.
+func (x *Index) Remove(id string) bool {
+	x.init()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.remove(id)
+}
+
+func (x *Index) remove(id string) bool {
+	for i := range x.snippets {
+		if x.snippets[i].ID == id {
+			x.snippets = append(x.snippets[:i], x.snippets[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the snippet of the given ID.
+func (x *Index) Get(id string) (Snippet, bool) {
+	x.init()
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	for _, s := range x.snippets {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return Snippet{}, false
+}
+
+// Search returns the given page of the snippets that contain all words
+// of the query, ignoring case, and the number of all matches. Pages
+// start from 1, and an empty query matches all snippets.
+func (x *Index) Search(query string, page, size int) ([]Snippet, int) {
+	x.init()
+	words := strings.Fields(strings.ToLower(query))
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var matches []Snippet
+	for _, s := range x.snippets {
+		if contains(s.Code, words) {
+			matches = append(matches, s)
+		}
+	}
+	start := (page - 1) * size
+	if page < 1 || size < 1 || start >= len(matches) {
+		return []Snippet{}, len(matches)
+	}
+	end := min(start+size, len(matches))
+	return matches[start:end:end], len(matches)
+}
+
+func contains(code string, words []string) bool {
+	if len(words) == 0 {
+		return true
+	}
+	code = strings.ToLower(code)
+	for _, w := range words {
+		if !strings.Contains(code, w) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package gallery_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"changkun.de/x/midgard/internal/gallery"
+)
+
+func ids(snippets []gallery.Snippet) string {
+	s := ""
+	for _, sn := range snippets {
+		s += sn.ID + " "
+	}
+	return s
+}
+
+func TestIndex(t *testing.T) {
+	base := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	loads := 0
+	x := gallery.New(func() ([]gallery.Snippet, error) {
+		loads++
+		var snippets []gallery.Snippet
+		for i := 0; i < 5; i++ {
+			snippets = append(snippets, gallery.Snippet{
+				ID:   fmt.Sprint(i),
+				Time: base.Add(time.Duration(i) * time.Minute),
+				Code: fmt.Sprintf("func f%d() {}", i),
+			})
+		}
+		snippets[2].Code = "package main\n\nfunc main() { println(\"Hello\") }"
+		return snippets, nil
+	})
+
+	got, total := x.Search("", 1, 2)
+	if ids(got) != "4 3 " || total != 5 {
+		t.Fatalf("unexpected first page: %q of %d", ids(got), total)
+	}
+	if got, _ := x.Search("", 3, 2); ids(got) != "0 " {
+		t.Fatalf("unexpected last page: %q", ids(got))
+	}
+	if got, _ := x.Search("", 4, 2); len(got) != 0 {
+		t.Fatalf("unexpected page after the last: %q", ids(got))
+	}
+	if got, total := x.Search("MAIN hello", 1, 10); ids(got) != "2 " || total != 1 {
+		t.Fatalf("unexpected search result: %q of %d", ids(got), total)
+	}
+	if got, _ := x.Search("main missing", 1, 10); len(got) != 0 {
+		t.Fatalf("all words must match, got %q", ids(got))
+	}
+
+	x.Add(gallery.Snippet{ID: "5", Time: base.Add(90 * time.Second), Code: "x"})
+	if !x.Remove("3") || x.Remove("3") {
+		t.Fatalf("unexpected removal")
+	}
+	if got, _ := x.Search("", 1, 10); ids(got) != "4 2 5 1 0 " {
+		t.Fatalf("unexpected order: %q", ids(got))
+	}
+	if s, ok := x.Get("5"); !ok || s.Code != "x" {
+		t.Fatalf("cannot get a snippet: %+v", s)
+	}
+	if loads != 1 {
+		t.Fatalf("snippets are loaded %d times", loads)
+	}
+}
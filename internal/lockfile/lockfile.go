@@ -0,0 +1,38 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package lockfile locks files across processes, e.g. so that a data
+// folder is only used by one process at a time.
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrLocked indicates a file that is locked by another process.
+var ErrLocked = errors.New("file is locked by another process")
+
+// Lock locks the file of the given path, which is created if it does
+// not exist. The lock is held until unlock is called, or the process
+// exits.
+func Lock(path string) (unlock func() error, err error) {
+	err = os.MkdirAll(filepath.Dir(path), fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create lock folder: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open lock file: %w", err)
+	}
+	err = lock(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f.Close, nil // closing the file releases the lock
+}
@@ -0,0 +1,14 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+//go:build !unix && !windows
+
+package lockfile
+
+import "os"
+
+// lock does not lock on platforms without file locks.
+func lock(f *os.File) error {
+	return nil
+}
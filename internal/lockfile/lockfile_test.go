@@ -0,0 +1,32 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package lockfile_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"changkun.de/x/midgard/internal/lockfile"
+)
+
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "server.lock")
+	unlock, err := lockfile.Lock(path)
+	if err != nil {
+		t.Fatalf("cannot lock: %v", err)
+	}
+	if _, err := lockfile.Lock(path); !errors.Is(err, lockfile.ErrLocked) {
+		t.Fatalf("file is locked twice, err: %v", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("cannot unlock: %v", err)
+	}
+	unlock, err = lockfile.Lock(path)
+	if err != nil {
+		t.Fatalf("cannot lock an unlocked file: %v", err)
+	}
+	unlock()
+}
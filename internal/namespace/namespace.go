@@ -0,0 +1,157 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package namespace validates the paths of resources that clients
+// allocate and visit. A path is resolved in the namespace of a user,
+// which is the root folder of the repo for the user of auth.user, and
+// /~<user> for any additional users.
+package namespace
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Errors
+var (
+	ErrInvalid  = errors.New("invalid path")
+	ErrReserved = errors.New("reserved path")
+)
+
+const (
+	maxPath    = 1024 // bytes of a path
+	maxSegment = 255  // bytes of a file or folder name
+)
+
+// reserved are the top level folders of a namespace that midgard
+// manages itself, e.g. the results of code2img.
+var reserved = []string{"code"}
+
+// Clean normalizes a slash separated path requested by a client to an
+// absolute path without a trailing slash, e.g. a//b/ becomes /a/b, and
+// an empty path becomes /. Paths that may escape their folder or reach
+// internal files are invalid, i.e. paths of dot segments, such as .. or
+// .git, of backslashes, which separate paths on Windows, or of NUL.
+// Any other names are valid, since existing resources may have them,
+// see Allocate for the names of new resources.
+func Clean(p string) (string, error) {
+	var segments []string
+	for _, s := range strings.Split(p, "/") {
+		if s == "" {
+			continue
+		}
+		if strings.HasPrefix(s, ".") {
+			return "", fmt.Errorf("%w: %q contains a dot segment", ErrInvalid, p)
+		}
+		if strings.ContainsAny(s, "\\\x00") {
+			return "", fmt.Errorf("%w: %q contains a backslash or NUL", ErrInvalid, p)
+		}
+		segments = append(segments, s)
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// checkName checks the name of a file or folder of a new resource.
+func checkName(s string) error {
+	if len(s) > maxSegment {
+		return fmt.Errorf("contains a name longer than %d bytes", maxSegment)
+	}
+	if strings.TrimSpace(s) != s {
+		return errors.New("contains a name with surrounding spaces")
+	}
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) ||
+			r == ' ' || strings.ContainsRune("-_.~+=@,()", r) {
+			continue
+		}
+		return fmt.Errorf("contains %q", r)
+	}
+	return nil
+}
+
+// CheckName checks a single name of a path, e.g. of a user or a channel,
+// with the rules of Allocate.
+func CheckName(s string) error {
+	if s == "" || strings.HasPrefix(s, ".") || !utf8.ValidString(s) {
+		return fmt.Errorf("%w: %q is not a name", ErrInvalid, s)
+	}
+	if err := checkName(s); err != nil {
+		return fmt.Errorf("%w: %q %v", ErrInvalid, s, err)
+	}
+	return nil
+}
+
+// User returns the namespace of the given additional user, /~<user>.
+// The name of the user must be a valid name without ~.
+func User(name string) (string, error) {
+	if strings.Contains(name, "~") {
+		return "", fmt.Errorf("%w: %q contains ~", ErrInvalid, name)
+	}
+	if err := CheckName(name); err != nil {
+		return "", err
+	}
+	return "/~" + name, nil
+}
+
+// Reserved reports whether the given cleaned path relative to a
+// namespace is reserved by midgard, i.e. a reserved folder or the
+// namespace of a user.
+func Reserved(p string) bool {
+	top := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)[0]
+	if strings.HasPrefix(top, "~") {
+		return true
+	}
+	for _, r := range reserved {
+		if top == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve resolves the path requested by a client in the namespace ns
+// to the path relative to the repo, e.g. for visiting or deleting a
+// resource.
+func Resolve(ns, p string) (string, error) {
+	p, err := Clean(p)
+	if err != nil {
+		return "", err
+	}
+	if p == "/" && ns != "" {
+		return ns, nil
+	}
+	return ns + p, nil
+}
+
+// Allocate is like Resolve, but for a path that a client allocates a
+// resource at, which is neither the namespace itself nor reserved. Names
+// of other characters than letters, digits, spaces and -_.~+=@,() are
+// invalid.
+func Allocate(ns, p string) (string, error) {
+	c, err := Clean(p)
+	if err != nil {
+		return "", err
+	}
+	if c == "/" {
+		return "", fmt.Errorf("%w: %q has no name", ErrInvalid, p)
+	}
+	if len(c) > maxPath {
+		return "", fmt.Errorf("%w: longer than %d bytes", ErrInvalid, maxPath)
+	}
+	if !utf8.ValidString(c) {
+		return "", fmt.Errorf("%w: %q is not UTF-8", ErrInvalid, p)
+	}
+	for _, s := range strings.Split(c[1:], "/") {
+		if err := checkName(s); err != nil {
+			return "", fmt.Errorf("%w: %q %v", ErrInvalid, p, err)
+		}
+	}
+	if Reserved(c) {
+		return "", fmt.Errorf("%w: %q", ErrReserved, p)
+	}
+	return ns + c, nil
+}
@@ -0,0 +1,156 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package namespace_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"changkun.de/x/midgard/internal/namespace"
+)
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"a", "/a"},
+		{"//a//b/", "/a/b"},
+		{"/random/fboVP8u4xNMHfvsv2EeLzL.png", "/random/fboVP8u4xNMHfvsv2EeLzL.png"},
+		{"/notes/2021-03-01 todo (v2).txt", "/notes/2021-03-01 todo (v2).txt"},
+		{"/a..b/c.tar.gz", "/a..b/c.tar.gz"},
+		{"/写真/猫.jpg", "/写真/猫.jpg"},
+		{"/~alice/a", "/~alice/a"},
+		{"/a?b/c#d", "/a?b/c#d"},
+		{"/ a /%2e%2e", "/ a /%2e%2e"},
+	}
+	for _, tt := range tests {
+		got, err := namespace.Clean(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Clean(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// malicious are paths that must never resolve, they escape the
+// namespace, or reach internal files.
+var malicious = []string{
+	"..",
+	"../x",
+	"/../x",
+	"/a/../../x",
+	"a/b/../../../x",
+	"/~alice/../x",
+	".",
+	"./x",
+	"/a/./b",
+	"...",
+	".git",
+	"/.git/config",
+	"/a/.git/HEAD",
+	"/.midgard/index.yml",
+	"/.htaccess",
+	`..\x`,
+	`a\..\..\x`,
+	`C:\x`,
+	"/a\x00b",
+}
+
+// unsafe are names that may confuse the file system or clients, new
+// resources cannot be allocated at them.
+var unsafe = []string{
+	"%2e%2e/x",
+	"/a%2fb",
+	"C:/x",
+	"/a\nb",
+	"/a\tb",
+	"/a?b",
+	"/a#b",
+	"/a*b",
+	"/a<b>",
+	"/a|b",
+	"/a\"b",
+	"/a'b",
+	"/a b /c",
+	"/ a",
+	"/a\u202eb", // right-to-left override
+	"/a\xffb",
+	"/" + strings.Repeat("a", 256),
+	strings.Repeat("/a", 513),
+}
+
+func TestMalicious(t *testing.T) {
+	for _, p := range unsafe {
+		for _, ns := range []string{"", "/~alice"} {
+			if _, err := namespace.Resolve(ns, p); err != nil {
+				t.Errorf("Resolve(%q, %q) is invalid: %v", ns, p, err)
+			}
+			if got, err := namespace.Allocate(ns, p); !errors.Is(err, namespace.ErrInvalid) {
+				t.Errorf("Allocate(%q, %q) = %q, %v, want invalid", ns, p, got, err)
+			}
+		}
+	}
+	for _, p := range malicious {
+		for _, ns := range []string{"", "/~alice"} {
+			if got, err := namespace.Resolve(ns, p); !errors.Is(err, namespace.ErrInvalid) {
+				t.Errorf("Resolve(%q, %q) = %q, %v, want invalid", ns, p, got, err)
+			}
+			if got, err := namespace.Allocate(ns, p); !errors.Is(err, namespace.ErrInvalid) {
+				t.Errorf("Allocate(%q, %q) = %q, %v, want invalid", ns, p, got, err)
+			}
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		ns, in, want string
+	}{
+		{"", "", "/"},
+		{"", "/code/210301-120000.png", "/code/210301-120000.png"},
+		{"/~alice", "/", "/~alice"},
+		{"/~alice", "a/b", "/~alice/a/b"},
+		{"/~alice", "/~bob/x", "/~alice/~bob/x"},
+	}
+	for _, tt := range tests {
+		got, err := namespace.Resolve(tt.ns, tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q, %q) = %q, %v, want %q", tt.ns, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	for _, p := range []string{"", "/", "//"} {
+		if _, err := namespace.Allocate("", p); !errors.Is(err, namespace.ErrInvalid) {
+			t.Errorf("Allocate(%q) is not invalid: %v", p, err)
+		}
+	}
+	for _, p := range []string{"code", "/code/x.png", "/~bob", "/~alice/x", "~/x"} {
+		for _, ns := range []string{"", "/~alice"} {
+			if got, err := namespace.Allocate(ns, p); !errors.Is(err, namespace.ErrReserved) {
+				t.Errorf("Allocate(%q, %q) = %q, %v, want reserved", ns, p, got, err)
+			}
+		}
+	}
+	got, err := namespace.Allocate("/~alice", "/a/code/x~1.png")
+	if err != nil || got != "/~alice/a/code/x~1.png" {
+		t.Errorf("Allocate = %q, %v", got, err)
+	}
+}
+
+func TestUser(t *testing.T) {
+	for _, name := range []string{"", "..", ".alice", "a/b", "~alice", "a~b", `a\b`, "a:b"} {
+		if ns, err := namespace.User(name); !errors.Is(err, namespace.ErrInvalid) {
+			t.Errorf("User(%q) = %q, %v, want invalid", name, ns, err)
+		}
+	}
+	ns, err := namespace.User("alice")
+	if err != nil || ns != "/~alice" {
+		t.Errorf("User(alice) = %q, %v", ns, err)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package namespace
+
+import "strings"
+
+// Policy decides which resources are served publicly, by rules of the
+// top level folders of namespaces, e.g. a rule of random applies to
+// both /random and /~alice/random. Internal files, whose names start
+// with a dot, such as the .git folder of the backup, are never public.
+type Policy struct {
+	Default bool     // whether folders without rules are public
+	Allow   []string // public top level folders
+	Deny    []string // private top level folders, precedes Allow
+}
+
+// Public reports whether the resource of the given path relative to
+// the repo is served publicly.
+func (p Policy) Public(rel string) bool {
+	segments := strings.Split(strings.Trim(rel, "/"), "/")
+	for _, s := range segments {
+		if strings.HasPrefix(s, ".") {
+			return false
+		}
+	}
+	if strings.HasPrefix(segments[0], "~") {
+		segments = segments[1:]
+	}
+	if len(segments) == 0 || segments[0] == "" {
+		return true // the root of a namespace
+	}
+	top := segments[0]
+	for _, d := range p.Deny {
+		if strings.Trim(d, "/") == top {
+			return false
+		}
+	}
+	for _, a := range p.Allow {
+		if strings.Trim(a, "/") == top {
+			return true
+		}
+	}
+	return p.Default
+}
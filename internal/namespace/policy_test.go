@@ -0,0 +1,44 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package namespace_test
+
+import (
+	"testing"
+
+	"changkun.de/x/midgard/internal/namespace"
+)
+
+func TestPolicy(t *testing.T) {
+	allow := namespace.Policy{Default: true, Deny: []string{"private"}}
+	deny := namespace.Policy{Allow: []string{"random", "/code/"}, Deny: []string{"code"}}
+
+	tests := []struct {
+		rel         string
+		allow, deny bool
+	}{
+		{"/", true, true},
+		{"/~alice", true, true},
+		{"/random/abc.png", true, true},
+		{"/~alice/random/abc.png", true, true},
+		{"/code/210301-120000.png", true, false},
+		{"/img/a.png", true, false},
+		{"/private/a.txt", false, false},
+		{"/~alice/private/a.txt", false, false},
+		{"/private", false, false},
+		{"/.git/config", false, false},
+		{"/.git", false, false},
+		{"/.midgard/index.yml", false, false},
+		{"/random/.gitkeep", false, false},
+		{"/~alice/.git/HEAD", false, false},
+	}
+	for _, tt := range tests {
+		if got := allow.Public(tt.rel); got != tt.allow {
+			t.Errorf("allow policy: Public(%q) = %v, want %v", tt.rel, got, tt.allow)
+		}
+		if got := deny.Public(tt.rel); got != tt.deny {
+			t.Errorf("deny policy: Public(%q) = %v, want %v", tt.rel, got, tt.deny)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package quota limits the storage that users of midgard consume. The
+// usage of each user is counted in bytes and files, and is limited per
+// user, in total, and per upload.
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"changkun.de/x/midgard/internal/utils"
+)
+
+// Errors
+var (
+	ErrTooLarge = errors.New("upload is too large")
+	ErrExceeded = errors.New("storage quota is exceeded")
+)
+
+// Usage is an amount of storage, as a limit a zero field is unlimited.
+type Usage struct {
+	Bytes int64
+	Files int
+}
+
+// fits reports whether the usage fits in the given limit.
+func (u Usage) fits(limit Usage) bool {
+	return (limit.Bytes <= 0 || u.Bytes <= limit.Bytes) &&
+		(limit.Files <= 0 || u.Files <= limit.Files)
+}
+
+// Limits are the limits of a quota.
+type Limits struct {
+	MaxUpload int64                   // bytes of a single upload, zero is unlimited
+	Total     Usage                   // of all users
+	User      func(name string) Usage // of a user
+}
+
+// Quota tracks the usage of users and enforces its limits. The usage is
+// counted by a scan on first use, and is tracked by reservations and
+// releases afterwards.
+type Quota struct {
+	limits Limits
+	scan   func() (map[string]Usage, error)
+
+	once  sync.Once
+	mu    sync.Mutex
+	users map[string]Usage
+	total Usage
+}
+
+// New creates a quota of the given limits, the initial usage of each
+// user is counted by the given scan.
+func New(limits Limits, scan func() (map[string]Usage, error)) *Quota {
+	return &Quota{limits: limits, scan: scan}
+}
+
+func (q *Quota) load() {
+	q.once.Do(func() {
+		users, err := q.scan()
+		if err != nil {
+			// the usage is underestimated, but uploads are not blocked.
+			log.Printf("cannot count storage usage: %v", err)
+		}
+		if users == nil {
+			users = map[string]Usage{}
+		}
+		q.users = users
+		for _, u := range users {
+			q.total.Bytes += u.Bytes
+			q.total.Files += u.Files
+		}
+	})
+}
+
+// Check checks whether a file of the given bytes fits in the quota of
+// the user, without reserving it.
+func (q *Quota) Check(user string, bytes int64) error {
+	q.load()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.check(user, bytes)
+}
+
+func (q *Quota) check(user string, bytes int64) error {
+	if q.limits.MaxUpload > 0 && bytes > q.limits.MaxUpload {
+		return fmt.Errorf("%w: %s exceeds the limit of %s", ErrTooLarge,
+			utils.ByteSize(bytes), utils.ByteSize(q.limits.MaxUpload))
+	}
+	u := q.users[user]
+	if limit := q.limit(user); !(Usage{u.Bytes + bytes, u.Files + 1}).fits(limit) {
+		return fmt.Errorf("%w: %s uses %s", ErrExceeded, user, format(u, limit))
+	}
+	if !(Usage{q.total.Bytes + bytes, q.total.Files + 1}).fits(q.limits.Total) {
+		return fmt.Errorf("%w: the server uses %s", ErrExceeded, format(q.total, q.limits.Total))
+	}
+	return nil
+}
+
+// Reserve reserves a file of the given bytes in the quota of the user.
+func (q *Quota) Reserve(user string, bytes int64) error {
+	q.load()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.check(user, bytes); err != nil {
+		return err
+	}
+	q.add(user, bytes, 1)
+	return nil
+}
+
+// Release releases a file of the given bytes from the quota of the user.
+func (q *Quota) Release(user string, bytes int64) {
+	q.load()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.add(user, -bytes, -1)
+}
+
+func (q *Quota) add(user string, bytes int64, files int) {
+	u := q.users[user]
+	u.Bytes += bytes
+	u.Files += files
+	q.users[user] = u
+	q.total.Bytes += bytes
+	q.total.Files += files
+}
+
+// Usage returns the usage and the limit of the user.
+func (q *Quota) Usage(user string) (Usage, Usage) {
+	q.load()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.users[user], q.limit(user)
+}
+
+// Total returns the usage and the limit of all users.
+func (q *Quota) Total() (Usage, Usage) {
+	q.load()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.total, q.limits.Total
+}
+
+// MaxUpload returns the maximum bytes of a single upload, zero is
+// unlimited.
+func (q *Quota) MaxUpload() int64 {
+	return q.limits.MaxUpload
+}
+
+func (q *Quota) limit(user string) Usage {
+	if q.limits.User == nil {
+		return Usage{}
+	}
+	return q.limits.User(user)
+}
+
+// format formats the given usage and its limit.
+func format(u, limit Usage) string {
+	s := utils.ByteSize(u.Bytes)
+	if limit.Bytes > 0 {
+		s += " of " + utils.ByteSize(limit.Bytes)
+	}
+	s += fmt.Sprintf(" in %d files", u.Files)
+	if limit.Files > 0 {
+		s += fmt.Sprintf(" of %d", limit.Files)
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package quota_test
+
+import (
+	"errors"
+	"testing"
+
+	"changkun.de/x/midgard/internal/quota"
+)
+
+func TestQuota(t *testing.T) {
+	q := quota.New(quota.Limits{
+		MaxUpload: 100,
+		Total:     quota.Usage{Bytes: 300},
+		User: func(name string) quota.Usage {
+			if name == "alice" {
+				return quota.Usage{Bytes: 150, Files: 2}
+			}
+			return quota.Usage{}
+		},
+	}, func() (map[string]quota.Usage, error) {
+		return map[string]quota.Usage{"alice": {Bytes: 50, Files: 1}}, nil
+	})
+
+	if err := q.Check("alice", 101); !errors.Is(err, quota.ErrTooLarge) {
+		t.Fatalf("large upload is allowed, err: %v", err)
+	}
+	if err := q.Reserve("alice", 100); err != nil {
+		t.Fatalf("cannot reserve: %v", err)
+	}
+	if err := q.Reserve("alice", 1); !errors.Is(err, quota.ErrExceeded) {
+		t.Fatalf("user quota is not enforced, err: %v", err)
+	}
+	if u, limit := q.Usage("alice"); u != (quota.Usage{Bytes: 150, Files: 2}) || limit.Bytes != 150 {
+		t.Fatalf("unexpected usage: %+v of %+v", u, limit)
+	}
+
+	// users without limits are still limited by the total quota
+	if err := q.Reserve("bob", 100); err != nil {
+		t.Fatalf("cannot reserve: %v", err)
+	}
+	if err := q.Check("bob", 51); !errors.Is(err, quota.ErrExceeded) {
+		t.Fatalf("total quota is not enforced, err: %v", err)
+	}
+
+	q.Release("alice", 100)
+	if err := q.Reserve("alice", 100); err != nil {
+		t.Fatalf("released quota is not available: %v", err)
+	}
+	if total, _ := q.Total(); total != (quota.Usage{Bytes: 250, Files: 3}) {
+		t.Fatalf("unexpected total usage: %+v", total)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package resource
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// ErrEmptyPassword indicates an empty password.
+var ErrEmptyPassword = errors.New("password must not be empty")
+
+const (
+	hashScheme     = "pbkdf2-sha256"
+	hashIterations = 100000
+)
+
+// HashPassword hashes the given password with a random salt, the
+// password itself is never stored.
+func HashPassword(pass string) (string, error) {
+	if pass == "" {
+		return "", ErrEmptyPassword
+	}
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("cannot create salt: %w", err)
+	}
+	key := pbkdf2.Key([]byte(pass), salt, hashIterations, sha256.Size, sha256.New)
+	return fmt.Sprintf("%s$%d$%x$%x", hashScheme, hashIterations, salt, key), nil
+}
+
+func checkPassword(hash, pass string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return false
+	}
+	iter, err := strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got := pbkdf2.Key([]byte(pass), salt, iter, sha256.Size, sha256.New)
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
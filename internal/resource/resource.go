@@ -0,0 +1,396 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package resource keeps the metadata of allocated resources, such as
+// the content type, the expiration time, the view limit, and the access
+// password of a resource.
+package resource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"changkun.de/x/midgard/internal/storage"
+	"gopkg.in/yaml.v3"
+)
+
+// Meta is the metadata of an allocated resource.
+type Meta struct {
+	// Path is the path of the resource relative to the repo, e.g.
+	// /random/abc.txt, it is also the URL path of the resource.
+	Path    string    `yaml:"path"`
+	Created time.Time `yaml:"created"`
+	// Type is the content type of the resource, empty if unknown.
+	Type string `yaml:"type,omitempty"`
+	// Blob is the content hash of the resource if its storage is shared
+	// with resources of identical content, see package blob.
+	Blob string `yaml:"blob,omitempty"`
+	// Expires is the time that the resource expires, zero if never.
+	Expires time.Time `yaml:"expires,omitempty"`
+	// MaxViews is the number of allowed views, zero if unlimited.
+	MaxViews int `yaml:"max_views,omitempty"`
+	Views    int `yaml:"views,omitempty"`
+	// Password is the hash of the access password, empty if the
+	// resource is public, see HashPassword.
+	Password string `yaml:"password,omitempty"`
+}
+
+// Protected reports whether the resource requires a password.
+func (m Meta) Protected() bool {
+	return m.Password != ""
+}
+
+// CheckPassword reports whether the given password grants access to
+// the resource. Public resources grant access to any password.
+func (m Meta) CheckPassword(pass string) bool {
+	if !m.Protected() {
+		return true
+	}
+	return checkPassword(m.Password, pass)
+}
+
+// Ephemeral reports whether the resource is removed at some point.
+func (m Meta) Ephemeral() bool {
+	return !m.Expires.IsZero() || m.MaxViews > 0
+}
+
+// Expired reports whether the resource is expired at the given time,
+// or its views are used up.
+func (m Meta) Expired(now time.Time) bool {
+	if !m.Expires.IsZero() && !now.Before(m.Expires) {
+		return true
+	}
+	return m.MaxViews > 0 && m.Views >= m.MaxViews
+}
+
+// Index is a persisted index of resource metadata. Resources without
+// metadata are not necessarily in the index.
+//
+// Folders that ever held protected or ephemeral resources are guarded,
+// every resource in a guarded folder has metadata. A resource without
+// metadata in a guarded folder has lost its metadata, and must not be
+// served as if it were public and permanent.
+type Index struct {
+	mu      sync.Mutex
+	store   storage.Storage
+	dir     string
+	metas   map[string]*Meta
+	guarded map[string]bool // folders
+}
+
+const (
+	indexFile   = "index.yml"
+	guardedFile = "guarded.yml"
+)
+
+// Open opens the index that is persisted in the given folder of the
+// storage, the files are created on the first change if they do not
+// exist.
+func Open(s storage.Storage, dir string) (*Index, error) {
+	idx := &Index{store: s, dir: dir, metas: map[string]*Meta{}, guarded: map[string]bool{}}
+	var metas []Meta
+	err := idx.read(indexFile, &metas)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read resource index: %w", err)
+	}
+	for i := range metas {
+		idx.metas[metas[i].Path] = &metas[i]
+	}
+	var guarded []string
+	err = idx.read(guardedFile, &guarded)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read guarded folders: %w", err)
+	}
+	for _, dir := range guarded {
+		idx.guarded[dir] = true
+	}
+	return idx, nil
+}
+
+func (idx *Index) read(name string, v interface{}) error {
+	r, _, err := idx.store.Open(path.Join(idx.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, v)
+}
+
+// Put adds or replaces the metadata of a resource. The folder of a
+// protected or ephemeral resource is guarded, see Guarded.
+func (idx *Index) Put(m Meta) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	old, ok := idx.metas[m.Path]
+	dir, err := idx.prepareGuard(m)
+	if err != nil {
+		return err
+	}
+	idx.metas[m.Path] = &m
+	err = idx.persist()
+	if err == nil {
+		err = idx.guard(dir)
+	}
+	if err != nil {
+		if ok {
+			idx.metas[m.Path] = old
+		} else {
+			delete(idx.metas, m.Path)
+		}
+		return err
+	}
+	return nil
+}
+
+// prepareGuard returns the folder of the given metadata if the folder
+// needs to be guarded, and adds metadata for the other resources of the
+// folder so that they are still served once it is guarded. The folder
+// is guarded by guard after the index is persisted.
+func (idx *Index) prepareGuard(m Meta) (string, error) {
+	dir := path.Dir(m.Path)
+	if (!m.Ephemeral() && !m.Protected()) || idx.guarded[dir] {
+		return "", nil
+	}
+	infos, err := idx.store.List(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("cannot list resources of %s: %w", dir, err)
+	}
+	for _, info := range infos {
+		if _, ok := idx.metas[info.Key]; ok || info.Dir || info.Key == m.Path {
+			continue
+		}
+		idx.metas[info.Key] = &Meta{Path: info.Key, Created: info.ModTime.UTC()}
+	}
+	return dir, nil
+}
+
+// guard guards the given folder, nothing is guarded if it is empty.
+func (idx *Index) guard(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	idx.guarded[dir] = true
+	err := idx.persistGuarded()
+	if err != nil {
+		delete(idx.guarded, dir)
+		return err
+	}
+	return nil
+}
+
+func (idx *Index) persistGuarded() error {
+	dirs := make([]string, 0, len(idx.guarded))
+	for d := range idx.guarded {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+	err := idx.write(guardedFile, dirs)
+	if err != nil {
+		return fmt.Errorf("cannot save guarded folders: %w", err)
+	}
+	return nil
+}
+
+// Guarded reports whether the resource of the given path is in a
+// guarded folder, where a resource without metadata must not be served.
+func (idx *Index) Guarded(p string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.guarded[path.Dir(p)]
+}
+
+// Get returns the metadata of the resource of the given path.
+func (idx *Index) Get(path string) (Meta, bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	m, ok := idx.metas[path]
+	if !ok {
+		return Meta{}, false
+	}
+	return *m, true
+}
+
+// View counts a view of the resource of the given path at the given
+// time. It reports whether the view is allowed, and whether the view
+// used up the resource so that it should be removed after the view.
+// Resources that are not in the index can always be viewed.
+func (idx *Index) View(path string, now time.Time) (allowed, last bool) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	m, ok := idx.metas[path]
+	if !ok {
+		return true, false
+	}
+	if m.Expired(now) {
+		return false, false
+	}
+	if m.MaxViews == 0 {
+		return true, false
+	}
+	m.Views++
+	_ = idx.persist() // a lost count is better than a failed view
+	return true, m.Views >= m.MaxViews
+}
+
+// Delete removes the metadata of the resource of the given path.
+func (idx *Index) Delete(path string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	m, ok := idx.metas[path]
+	if !ok {
+		return nil
+	}
+	delete(idx.metas, path)
+	err := idx.persist()
+	if err != nil {
+		idx.metas[path] = m
+		return err
+	}
+	return nil
+}
+
+// Move moves the metadata of the resource of the given path to a new
+// path, any metadata of the new path is replaced. A resource without
+// metadata gets metadata if it is moved to a guarded folder.
+func (idx *Index) Move(from, to string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	m, ok := idx.metas[from]
+	if !ok && !idx.guarded[path.Dir(to)] {
+		return nil
+	}
+	moved := Meta{Created: time.Now().UTC()}
+	if ok {
+		moved = *m
+	}
+	moved.Path = to
+	old, replaced := idx.metas[to]
+	dir, err := idx.prepareGuard(moved)
+	if err != nil {
+		return err
+	}
+	delete(idx.metas, from)
+	idx.metas[to] = &moved
+	err = idx.persist()
+	if err == nil {
+		err = idx.guard(dir)
+	}
+	if err != nil {
+		if ok {
+			idx.metas[from] = m
+		}
+		if replaced {
+			idx.metas[to] = old
+		} else {
+			delete(idx.metas, to)
+		}
+		return err
+	}
+	return nil
+}
+
+// References returns the metadata of all resources of the given blob.
+func (idx *Index) References(blob string) []Meta {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var metas []Meta
+	for _, m := range idx.metas {
+		if m.Blob == blob {
+			metas = append(metas, *m)
+		}
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].Path < metas[j].Path
+	})
+	return metas
+}
+
+// Expired returns the paths of all expired resources at the given time.
+func (idx *Index) Expired(now time.Time) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var paths []string
+	for p, m := range idx.metas {
+		if m.Expired(now) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Files returns the keys of the files of the index in its storage.
+func (idx *Index) Files() []string {
+	return []string{path.Join(idx.dir, indexFile), path.Join(idx.dir, guardedFile)}
+}
+
+// Export writes a copy of the index to the given folder of a storage,
+// which can be opened by Open. The copy only has the metadata that keep
+// returns true for, except of protected resources, whose password
+// hashes are never copied.
+func (idx *Index) Export(s storage.Storage, dir string, keep func(Meta) bool) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	cp := &Index{store: s, dir: dir, metas: map[string]*Meta{}, guarded: idx.guarded}
+	for p, m := range idx.metas {
+		if keep(*m) && !m.Protected() {
+			m := *m
+			cp.metas[p] = &m
+		}
+	}
+	err := cp.persist()
+	if err == nil {
+		err = cp.persistGuarded()
+	}
+	return err
+}
+
+func (idx *Index) persist() error {
+	metas := make([]Meta, 0, len(idx.metas))
+	for _, m := range idx.metas {
+		metas = append(metas, *m)
+	}
+	sort.Slice(metas, func(i, j int) bool {
+		return metas[i].Path < metas[j].Path
+	})
+	err := idx.write(indexFile, metas)
+	if err != nil {
+		return fmt.Errorf("cannot save resource index: %w", err)
+	}
+	return nil
+}
+
+// write writes the value as YAML to the file of the given name. Local
+// files are only readable by the server, the index holds password
+// hashes.
+func (idx *Index) write(name string, v interface{}) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	key := path.Join(idx.dir, name)
+	err = idx.store.Put(key, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if l, ok := idx.store.(storage.Local); ok {
+		return os.Chmod(l.Path(key), 0600)
+	}
+	return nil
+}
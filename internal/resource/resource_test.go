@@ -0,0 +1,175 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package resource_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"changkun.de/x/midgard/internal/resource"
+	"changkun.de/x/midgard/internal/storage"
+)
+
+func TestIndex(t *testing.T) {
+	s := storage.NewFS(t.TempDir())
+	idx, err := resource.Open(s, "/.midgard")
+	if err != nil {
+		t.Fatalf("cannot open index: %v", err)
+	}
+
+	now := time.Now().UTC()
+	metas := []resource.Meta{
+		{Path: "/forever.txt", Created: now},
+		{Path: "/expire.txt", Created: now, Expires: now.Add(time.Hour)},
+		{Path: "/once.png", Created: now, MaxViews: 1},
+	}
+	for _, m := range metas {
+		if err := idx.Put(m); err != nil {
+			t.Fatalf("cannot put %s: %v", m.Path, err)
+		}
+	}
+
+	// the index must survive a restart
+	idx, err = resource.Open(s, "/.midgard")
+	if err != nil {
+		t.Fatalf("cannot reopen index: %v", err)
+	}
+	for _, m := range metas {
+		got, ok := idx.Get(m.Path)
+		if !ok || !got.Expires.Equal(m.Expires) || got.MaxViews != m.MaxViews {
+			t.Fatalf("inconsistent metadata of %s, got: %+v", m.Path, got)
+		}
+	}
+
+	if allowed, last := idx.View("/once.png", now); !allowed || !last {
+		t.Fatalf("the only view is not allowed or not the last one")
+	}
+	if allowed, _ := idx.View("/once.png", now); allowed {
+		t.Fatalf("views are not limited")
+	}
+	if allowed, last := idx.View("/unknown.txt", now); !allowed || last {
+		t.Fatalf("a resource without metadata must be viewable")
+	}
+
+	want := []string{"/expire.txt", "/once.png"}
+	if got := idx.Expired(now.Add(2 * time.Hour)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected expired resources, want %v, got %v", want, got)
+	}
+	if err := idx.Move("/forever.txt", "/moved/forever.txt"); err != nil {
+		t.Fatalf("cannot move metadata: %v", err)
+	}
+	if m, ok := idx.Get("/moved/forever.txt"); !ok || m.Path != "/moved/forever.txt" {
+		t.Fatalf("metadata is not moved, got: %+v", m)
+	}
+	if _, ok := idx.Get("/forever.txt"); ok {
+		t.Fatalf("moved metadata is still in the old path")
+	}
+	if err := idx.Delete("/expire.txt"); err != nil {
+		t.Fatalf("cannot delete metadata: %v", err)
+	}
+	if _, ok := idx.Get("/expire.txt"); ok {
+		t.Fatalf("deleted metadata is still in the index")
+	}
+}
+
+func TestIndexGuarded(t *testing.T) {
+	s := storage.NewFS(t.TempDir())
+	for _, key := range []string{"/random/old.txt", "/random/secret.txt", "/legacy.txt"} {
+		if err := s.Put(key, strings.NewReader(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx, err := resource.Open(s, "/.midgard")
+	if err != nil {
+		t.Fatalf("cannot open index: %v", err)
+	}
+	if idx.Guarded("/random/old.txt") {
+		t.Fatalf("folders without protected resources are guarded")
+	}
+	err = idx.Put(resource.Meta{Path: "/random/secret.txt", Created: time.Now().UTC(), Password: "hash"})
+	if err != nil {
+		t.Fatalf("cannot put metadata: %v", err)
+	}
+
+	// guards survive a restart, and existing resources keep being served.
+	idx, err = resource.Open(s, "/.midgard")
+	if err != nil {
+		t.Fatalf("cannot reopen index: %v", err)
+	}
+	if !idx.Guarded("/random/lost.txt") || idx.Guarded("/legacy.txt") {
+		t.Fatalf("unexpected guarded folders")
+	}
+	if m, ok := idx.Get("/random/old.txt"); !ok || m.Protected() {
+		t.Fatalf("existing resource of a guarded folder has no metadata: %+v", m)
+	}
+
+	if err := idx.Move("/legacy.txt", "/random/legacy.txt"); err != nil {
+		t.Fatalf("cannot move metadata: %v", err)
+	}
+	if _, ok := idx.Get("/random/legacy.txt"); !ok {
+		t.Fatalf("resource moved to a guarded folder has no metadata")
+	}
+}
+
+func TestIndexExport(t *testing.T) {
+	s := storage.NewFS(t.TempDir())
+	idx, err := resource.Open(s, "/.midgard")
+	if err != nil {
+		t.Fatalf("cannot open index: %v", err)
+	}
+	now := time.Now().UTC()
+	for _, m := range []resource.Meta{
+		{Path: "/public.txt", Created: now},
+		{Path: "/a/once.txt", Created: now, MaxViews: 1},
+		{Path: "/b/secret.txt", Created: now, Password: "hash"},
+	} {
+		if err := idx.Put(m); err != nil {
+			t.Fatalf("cannot put %s: %v", m.Path, err)
+		}
+	}
+	err = idx.Export(s, "/.midgard/backup", func(m resource.Meta) bool { return !m.Ephemeral() })
+	if err != nil {
+		t.Fatalf("cannot export index: %v", err)
+	}
+
+	cp, err := resource.Open(s, "/.midgard/backup")
+	if err != nil {
+		t.Fatalf("cannot open the copy: %v", err)
+	}
+	if _, ok := cp.Get("/a/once.txt"); ok {
+		t.Fatalf("the copy has metadata that is not kept")
+	}
+	if m, ok := cp.Get("/b/secret.txt"); ok {
+		t.Fatalf("the copy has a password hash: %+v", m)
+	}
+	if _, ok := cp.Get("/public.txt"); !ok {
+		t.Fatalf("the copy lost metadata")
+	}
+	if !cp.Guarded("/a/x") || !cp.Guarded("/b/x") {
+		t.Fatalf("the copy lost the guarded folders")
+	}
+}
+
+func TestPassword(t *testing.T) {
+	hash, err := resource.HashPassword("secret")
+	if err != nil {
+		t.Fatalf("cannot hash password: %v", err)
+	}
+	m := resource.Meta{Path: "/secret.txt", Password: hash}
+	if !m.Protected() || !m.CheckPassword("secret") {
+		t.Fatalf("correct password is rejected")
+	}
+	if m.CheckPassword("Secret") || m.CheckPassword("") {
+		t.Fatalf("wrong password is accepted")
+	}
+	if !(resource.Meta{}).CheckPassword("") {
+		t.Fatalf("public resource requires a password")
+	}
+	if _, err := resource.HashPassword(""); err == nil {
+		t.Fatalf("empty password is accepted")
+	}
+}
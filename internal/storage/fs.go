@@ -0,0 +1,158 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// FS is a storage in a folder of the local filesystem.
+type FS struct {
+	root string
+}
+
+var _ Local = &FS{}
+
+// NewFS creates a storage in the given folder.
+func NewFS(root string) *FS {
+	return &FS{root: root}
+}
+
+// Path returns the local path of the object of the given key.
+func (s *FS) Path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(clean(key)))
+}
+
+func (s *FS) info(key string, fi fs.FileInfo) Info {
+	return Info{
+		Key:     clean(key),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime().UTC(),
+		Dir:     fi.IsDir(),
+	}
+}
+
+// Stat returns the info of the object or folder of the given key.
+func (s *FS) Stat(key string) (Info, error) {
+	fi, err := os.Stat(s.Path(key))
+	if err != nil {
+		return Info{}, err
+	}
+	return s.info(key, fi), nil
+}
+
+// Open opens the object of the given key for reading.
+func (s *FS) Open(key string) (io.ReadSeekCloser, Info, error) {
+	f, err := os.Open(s.Path(key))
+	if err != nil {
+		return nil, Info{}, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Info{}, err
+	}
+	return f, s.info(key, fi), nil
+}
+
+// Put stores the data of the reader as the object of the given key.
+// An existing object is replaced rather than overwritten, which keeps
+// files that are hard links of the object intact.
+func (s *FS) Put(key string, r io.Reader) error {
+	p, err := s.mkdir(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op after the rename
+	_, err = io.Copy(f, r)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), p)
+}
+
+// Append appends the data to the object of the given key.
+func (s *FS) Append(key string, data []byte) error {
+	p, err := s.mkdir(key)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, fs.ModePerm)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	return err
+}
+
+// Import moves the local file of the given path to the object of the
+// given key. The file must be on the same filesystem as the storage.
+func (s *FS) Import(key, path string) error {
+	p, err := s.mkdir(key)
+	if err != nil {
+		return err
+	}
+	return os.Rename(path, p)
+}
+
+// Rename moves the object of the given key to another key.
+func (s *FS) Rename(from, to string) error {
+	p, err := s.mkdir(to)
+	if err != nil {
+		return err
+	}
+	return os.Rename(s.Path(from), p)
+}
+
+// Remove removes the object or the empty folder of the given key.
+func (s *FS) Remove(key string) error {
+	return os.Remove(s.Path(key))
+}
+
+// List lists the objects and folders in the folder of the given key.
+func (s *FS) List(key string) ([]Info, error) {
+	entries, err := os.ReadDir(s.Path(key))
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]Info, 0, len(entries))
+	for _, e := range entries {
+		fi, err := e.Info()
+		if err != nil {
+			continue // removed in the meantime
+		}
+		infos = append(infos, s.info(path.Join(clean(key), e.Name()), fi))
+	}
+	return infos, nil
+}
+
+// mkdir creates the parent folder of the given key and returns the
+// local path of the key.
+func (s *FS) mkdir(key string) (string, error) {
+	p := s.Path(key)
+	err := os.MkdirAll(filepath.Dir(p), fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return "", fmt.Errorf("cannot create folder: %w", err)
+	}
+	return p, nil
+}
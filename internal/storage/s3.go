@@ -0,0 +1,682 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config is the configuration of an S3 compatible object storage.
+type S3Config struct {
+	// Endpoint is the URL of the object storage, e.g.
+	// https://s3.eu-central-1.amazonaws.com or http://localhost:9000.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// S3 is a storage in a bucket of an S3 compatible object storage. The
+// bucket is addressed in path style, and objects are signed with the
+// AWS signature version 4.
+//
+// Objects are immutable, hence appended data are stored as segments, one
+// object per append, named after the object and the time of the append,
+// e.g. logs/2021/3/1.log#01614600000000000000-3f2a9c1d. An object of
+// segments is read as the concatenation of its segments.
+type S3 struct {
+	conf   S3Config
+	prefix string // key prefix of all objects, without slashes at both ends
+	client *http.Client
+}
+
+// NewS3 creates a storage of the objects in the given bucket whose keys
+// start with the given prefix.
+func NewS3(conf S3Config, prefix string) (*S3, error) {
+	u, err := url.Parse(conf.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint: %q", conf.Endpoint)
+	}
+	if conf.Bucket == "" {
+		return nil, errors.New("missing s3 bucket")
+	}
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+	conf.Endpoint = strings.TrimSuffix(conf.Endpoint, "/")
+	return &S3{
+		conf:   conf,
+		prefix: strings.Trim(prefix, "/"),
+		client: &http.Client{},
+	}, nil
+}
+
+// object returns the object name of the given key.
+func (s *S3) object(key string) string {
+	k := strings.TrimPrefix(clean(key), "/")
+	if s.prefix == "" {
+		return k
+	}
+	if k == "" {
+		return s.prefix
+	}
+	return s.prefix + "/" + k
+}
+
+// key is the reverse of object.
+func (s *S3) key(object string) string {
+	return clean(strings.TrimPrefix(object, s.prefix))
+}
+
+// segmentSep separates the name of an object from the names of its
+// segments, it is not a valid character of resource paths.
+const segmentSep = "#"
+
+// segment returns a new object name of a segment of the given object,
+// the segments of an object are sorted in the order of their appends.
+func segment(object string, t time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s%020d-%x", object, segmentSep, t.UnixNano(), b), nil
+}
+
+// segmentOf returns the object name of the given segment, and false if
+// the given object is not a segment.
+func segmentOf(object string) (string, bool) {
+	i := strings.LastIndex(object, segmentSep)
+	if i < 0 {
+		return "", false
+	}
+	stamp, suffix, ok := strings.Cut(object[i+1:], "-")
+	if !ok || len(stamp) != 20 || len(suffix) != 8 || strings.Trim(stamp, "0123456789") != "" {
+		return "", false
+	}
+	return object[:i], true
+}
+
+// s3Part is an object of the data of a key, either the whole data or a
+// segment of it.
+type s3Part struct {
+	object string
+	size   int64
+}
+
+// stat returns the info and the parts of the object of the given key.
+func (s *S3) stat(key string) (Info, []s3Part, error) {
+	resp, err := s.do(http.MethodHead, s.object(key), nil, nil, nil)
+	if err == nil {
+		resp.Body.Close()
+		info := s.info(key, resp)
+		return info, []s3Part{{s.object(key), info.Size}}, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return Info{}, nil, err
+	}
+
+	segs, err := s.segments(key)
+	if err != nil {
+		return Info{}, nil, err
+	}
+	if len(segs) == 0 {
+		return Info{}, nil, s.notExist(key)
+	}
+	info := Info{Key: clean(key)}
+	parts := make([]s3Part, 0, len(segs))
+	for _, o := range segs {
+		parts = append(parts, s3Part{o.Key, o.Size})
+		info.Size += o.Size
+		if mod, _ := time.Parse(time.RFC3339, o.LastModified); mod.After(info.ModTime) {
+			info.ModTime = mod.UTC()
+		}
+	}
+	return info, parts, nil
+}
+
+// segments returns the segments of the object of the given key, sorted
+// in the order of their appends.
+func (s *S3) segments(key string) ([]s3Object, error) {
+	objs, _, err := s.listPrefix(s.object(key)+segmentSep, 0)
+	if err != nil {
+		return nil, err
+	}
+	segs := objs[:0]
+	for _, o := range objs {
+		if name, ok := segmentOf(o.Key); ok && name == s.object(key) {
+			segs = append(segs, o)
+		}
+	}
+	return segs, nil
+}
+
+// removeSegments removes the segments of the object of the given key.
+func (s *S3) removeSegments(key string) error {
+	segs, err := s.segments(key)
+	if err != nil {
+		return err
+	}
+	for _, o := range segs {
+		if err := s.remove(o.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stat returns the info of the object or folder of the given key.
+func (s *S3) Stat(key string) (Info, error) {
+	if clean(key) == "/" {
+		return Info{Key: "/", Dir: true}, nil
+	}
+	info, _, err := s.stat(key)
+	if err == nil {
+		return info, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return Info{}, err
+	}
+
+	// folders only exist if there are objects in it.
+	objs, dirs, err := s.list(key, 1)
+	if err != nil {
+		return Info{}, err
+	}
+	if len(objs)+len(dirs) == 0 {
+		return Info{}, s.notExist(key)
+	}
+	return Info{Key: clean(key), Dir: true}, nil
+}
+
+func (s *S3) info(key string, resp *http.Response) Info {
+	mod, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return Info{
+		Key:     clean(key),
+		Size:    resp.ContentLength,
+		ModTime: mod.UTC(),
+	}
+}
+
+// Open opens the object of the given key for reading. The object is
+// downloaded on demand, seeks are translated to range requests.
+func (s *S3) Open(key string) (io.ReadSeekCloser, Info, error) {
+	info, parts, err := s.stat(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		if dir, err := s.Stat(key); err == nil && dir.Dir {
+			return nil, Info{}, fmt.Errorf("%s is a folder", key)
+		}
+	}
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return &s3Reader{s: s, parts: parts, size: info.Size}, info, nil
+}
+
+// Put stores the data of the reader as the object of the given key. The
+// data is buffered in memory, large files should be imported instead.
+func (s *S3) Put(key string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := s.put(s.object(key), data); err != nil {
+		return err
+	}
+	return s.removeSegments(key)
+}
+
+func (s *S3) put(object string, data []byte) error {
+	sum := sha256.Sum256(data)
+	resp, err := s.do(http.MethodPut, object, nil, http.Header{
+		"X-Amz-Content-Sha256": []string{hex.EncodeToString(sum[:])},
+	}, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Append appends the data to the object of the given key. Objects are
+// immutable, the data is uploaded as a new segment of the object, and an
+// existing object of a single part becomes its first segment.
+func (s *S3) Append(key string, data []byte) error {
+	resp, err := s.do(http.MethodHead, s.object(key), nil, nil, nil)
+	switch {
+	case err == nil:
+		resp.Body.Close()
+		first, err := segment(s.object(key), time.Unix(0, 0))
+		if err == nil {
+			err = s.copy(s.object(key), first)
+		}
+		if err == nil {
+			err = s.remove(s.object(key))
+		}
+		if err != nil {
+			return fmt.Errorf("cannot segment %s: %w", key, err)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	object, err := segment(s.object(key), time.Now())
+	if err != nil {
+		return err
+	}
+	return s.put(object, data)
+}
+
+// Import moves the local file of the given path to the object of the
+// given key, the file is streamed and removed after the upload.
+func (s *S3) Import(key, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPut, s.object(key), nil, http.Header{
+		"Content-Length":       []string{strconv.FormatInt(fi.Size(), 10)},
+		"X-Amz-Content-Sha256": []string{"UNSIGNED-PAYLOAD"},
+	}, f)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	f.Close()
+	if err := s.removeSegments(key); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Rename moves the object of the given key to another key, the object,
+// or each of its segments, is copied and then removed.
+func (s *S3) Rename(from, to string) error {
+	_, parts, err := s.stat(from)
+	if err != nil {
+		return err
+	}
+	if err := s.removeSegments(to); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		dst := s.object(to) + strings.TrimPrefix(p.object, s.object(from))
+		if err := s.copy(p.object, dst); err != nil {
+			return err
+		}
+	}
+	return s.Remove(from)
+}
+
+// copy copies the src object to the dst object.
+func (s *S3) copy(src, dst string) error {
+	resp, err := s.do(http.MethodPut, dst, nil, http.Header{
+		"X-Amz-Copy-Source": []string{escapePath("/" + s.conf.Bucket + "/" + src)},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Remove removes the object or the empty folder of the given key.
+func (s *S3) Remove(key string) error {
+	if clean(key) != "/" {
+		_, parts, err := s.stat(key)
+		if err == nil {
+			for _, p := range parts {
+				if err := s.remove(p.object); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if _, err := s.Stat(key); err != nil {
+		return err
+	}
+	// an empty folder does not exist, hence this one is not empty.
+	return fmt.Errorf("cannot remove %s: folder is not empty", key)
+}
+
+func (s *S3) remove(object string) error {
+	resp, err := s.do(http.MethodDelete, object, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// List lists the objects and folders in the folder of the given key.
+func (s *S3) List(key string) ([]Info, error) {
+	objs, dirs, err := s.list(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(objs)+len(dirs) == 0 && clean(key) != "/" {
+		return nil, s.notExist(key)
+	}
+	infos := make([]Info, 0, len(objs)+len(dirs))
+	for _, d := range dirs {
+		infos = append(infos, Info{Key: s.key(d), Dir: true})
+	}
+	// the segments of an object are listed as the object.
+	segmented := map[string]int{}
+	for _, o := range objs {
+		mod, _ := time.Parse(time.RFC3339, o.LastModified)
+		name, ok := segmentOf(o.Key)
+		if !ok {
+			infos = append(infos, Info{Key: s.key(o.Key), Size: o.Size, ModTime: mod.UTC()})
+			continue
+		}
+		i, ok := segmented[name]
+		if !ok {
+			i = len(infos)
+			segmented[name] = i
+			infos = append(infos, Info{Key: s.key(name)})
+		}
+		infos[i].Size += o.Size
+		if mod.After(infos[i].ModTime) {
+			infos[i].ModTime = mod.UTC()
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Key < infos[j].Key
+	})
+	return infos, nil
+}
+
+type s3Object struct {
+	Key          string `xml:"Key"`
+	Size         int64  `xml:"Size"`
+	LastModified string `xml:"LastModified"`
+}
+
+type s3ListResult struct {
+	Contents              []s3Object                `xml:"Contents"`
+	CommonPrefixes        []struct{ Prefix string } `xml:"CommonPrefixes"`
+	IsTruncated           bool                      `xml:"IsTruncated"`
+	NextContinuationToken string                    `xml:"NextContinuationToken"`
+}
+
+// list lists the objects and the prefixes of sub-folders in the folder
+// of the given key, at most max entries if max is positive.
+func (s *S3) list(key string, max int) ([]s3Object, []string, error) {
+	prefix := s.object(key) + "/"
+	if prefix == "/" {
+		prefix = ""
+	}
+	return s.listPrefix(prefix, max)
+}
+
+// listPrefix lists the objects and the prefixes of sub-folders whose
+// names start with the given prefix, at most max entries if max is
+// positive.
+func (s *S3) listPrefix(prefix string, max int) ([]s3Object, []string, error) {
+	q := url.Values{
+		"list-type": []string{"2"},
+		"prefix":    []string{prefix},
+		"delimiter": []string{"/"},
+	}
+	if max > 0 {
+		q.Set("max-keys", strconv.Itoa(max))
+	}
+
+	var (
+		objs []s3Object
+		dirs []string
+	)
+	for {
+		resp, err := s.do(http.MethodGet, "", q, nil, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		var res s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot parse s3 object list: %w", err)
+		}
+		objs = append(objs, res.Contents...)
+		for _, p := range res.CommonPrefixes {
+			dirs = append(dirs, strings.TrimSuffix(p.Prefix, "/"))
+		}
+		if !res.IsTruncated || max > 0 {
+			return objs, dirs, nil
+		}
+		q.Set("continuation-token", res.NextContinuationToken)
+	}
+}
+
+func (s *S3) notExist(key string) error {
+	return &fs.PathError{Op: "stat", Path: clean(key), Err: fs.ErrNotExist}
+}
+
+// do sends a signed request of the given object in the bucket. It
+// returns an error that wraps fs.ErrNotExist if the object does not
+// exist, and an error for any other unsuccessful response.
+func (s *S3) do(method, object string, query url.Values, header http.Header, body io.Reader) (*http.Response, error) {
+	u := s.conf.Endpoint + escapePath("/"+s.conf.Bucket+"/"+object)
+	if object == "" {
+		u = s.conf.Endpoint + escapePath("/"+s.conf.Bucket)
+	}
+	if len(query) > 0 {
+		u += "?" + canonicalQuery(query)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if n := req.Header.Get("Content-Length"); n != "" {
+		req.ContentLength, _ = strconv.ParseInt(n, 10, 64)
+		req.Header.Del("Content-Length")
+	}
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	}
+	s.sign(req, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot request s3: %w", err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &fs.PathError{Op: strings.ToLower(method), Path: s.key(object), Err: fs.ErrNotExist}
+	}
+	return nil, fmt.Errorf("s3 %s %s: %s: %s", method, object, resp.Status, msg)
+}
+
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign signs the request with the AWS signature version 4, see
+// https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func (s *S3) sign(req *http.Request, now time.Time) {
+	date := now.Format("20060102")
+	stamp := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", stamp)
+
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if req.Header.Get("X-Amz-Copy-Source") != "" {
+		headers = append(headers, "x-amz-copy-source")
+	}
+	sort.Strings(headers)
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signed := strings.Join(headers, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signed,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	scope := date + "/" + s.conf.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + stamp + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.conf.SecretKey), date)
+	key = hmacSHA256(key, s.conf.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.conf.AccessKey, scope, signed, sig))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// escapePath escapes a path as required by the signature, every byte
+// except unreserved characters and slashes is escaped.
+func escapePath(p string) string {
+	var b strings.Builder
+	for _, c := range []byte(p) {
+		if c == '/' || unreserved(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery encodes the query sorted by keys, every byte except
+// unreserved characters is escaped.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			parts = append(parts, escapeQuery(k)+"="+escapeQuery(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func escapeQuery(s string) string {
+	return strings.ReplaceAll(escapePath(s), "/", "%2F")
+}
+
+func unreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// s3Reader reads the parts of an object by range requests.
+type s3Reader struct {
+	s     *S3
+	parts []s3Part
+	size  int64
+	off   int64
+	body  io.ReadCloser // nil if no request is in flight
+	end   int64         // the end offset of the part of the body
+}
+
+func (r *s3Reader) Read(p []byte) (int, error) {
+	if r.off >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		start := int64(0)
+		for _, part := range r.parts {
+			if r.off < start+part.size {
+				resp, err := r.s.do(http.MethodGet, part.object, nil, http.Header{
+					"Range": []string{fmt.Sprintf("bytes=%d-", r.off-start)},
+				}, nil)
+				if err != nil {
+					return 0, err
+				}
+				r.body, r.end = resp.Body, start+part.size
+				break
+			}
+			start += part.size
+		}
+	}
+	n, err := r.body.Read(p)
+	r.off += int64(n)
+	if errors.Is(err, io.EOF) {
+		switch {
+		case r.off < r.end:
+			err = io.ErrUnexpectedEOF
+		case r.off < r.size:
+			err = r.Close() // continue with the next part
+		}
+	}
+	return n, err
+}
+
+func (r *s3Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if offset != r.off {
+		r.Close()
+		r.off = offset
+	}
+	return r.off, nil
+}
+
+func (r *s3Reader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
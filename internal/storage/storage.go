@@ -0,0 +1,71 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package storage abstracts the persistence of midgard's data, such as
+// allocated resources and clipboard logs. Data are stored as objects of
+// slash separated keys, e.g. /random/abc.png, and folders are implied
+// by the keys of the objects.
+//
+// The storage is either a folder of the local filesystem, see NewFS, or
+// a bucket of an S3 compatible object storage, see NewS3.
+package storage
+
+import (
+	"io"
+	"path"
+	"time"
+)
+
+// Info describes an object or a folder of a storage.
+type Info struct {
+	Key     string // e.g. /random/abc.png
+	Size    int64
+	ModTime time.Time
+	Dir     bool
+}
+
+// Name returns the last element of the key.
+func (i Info) Name() string {
+	return path.Base(i.Key)
+}
+
+// Storage stores objects by keys. Errors of missing objects or folders
+// wrap fs.ErrNotExist.
+type Storage interface {
+	// Stat returns the info of the object or folder of the given key.
+	Stat(key string) (Info, error)
+	// Open opens the object of the given key for reading.
+	Open(key string) (io.ReadSeekCloser, Info, error)
+	// Put stores the data of the reader as the object of the given
+	// key, an existing object is replaced.
+	Put(key string, r io.Reader) error
+	// Append appends the data to the object of the given key, the object
+	// is created if it does not exist.
+	Append(key string, data []byte) error
+	// Import moves the local file of the given path to the object of
+	// the given key.
+	Import(key, path string) error
+	// Rename moves the object of the given key to another key.
+	Rename(from, to string) error
+	// Remove removes the object or the empty folder of the given key.
+	Remove(key string) error
+	// List lists the objects and folders in the folder of the given key,
+	// sorted by their keys.
+	List(key string) ([]Info, error)
+}
+
+// Local is implemented by storages that keep objects as files of the
+// local filesystem, which allows features such as git backup and
+// deduplication by hard links.
+type Local interface {
+	Storage
+	// Path returns the local path of the object of the given key.
+	Path(key string) string
+}
+
+// clean cleans the given key to an absolute slash separated key, a key
+// cannot escape the root of a storage.
+func clean(key string) string {
+	return path.Clean("/" + key)
+}
@@ -0,0 +1,65 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package thumbnail
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache keeps rendered thumbnails in memory, it evicts the least
+// recently used thumbnails once they exceed the size of the cache.
+type Cache struct {
+	max int // bytes
+
+	mu    sync.Mutex
+	size  int
+	lru   *list.List // of *entry, most recently used first
+	items map[string]*list.Element
+}
+
+type entry struct {
+	key string
+	b   []byte
+}
+
+// NewCache creates a cache of the given size in bytes.
+func NewCache(max int) *Cache {
+	return &Cache{max: max, lru: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get returns the thumbnail of the given key.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*entry).b, true
+}
+
+// Add adds the thumbnail of the given key. Thumbnails larger than the
+// cache are not cached.
+func (c *Cache) Add(key string, b []byte) {
+	if len(b) > c.max {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.size -= len(e.Value.(*entry).b)
+		c.lru.Remove(e)
+	}
+	c.items[key] = c.lru.PushFront(&entry{key: key, b: b})
+	c.size += len(b)
+	for c.size > c.max {
+		e := c.lru.Back()
+		c.lru.Remove(e)
+		delete(c.items, e.Value.(*entry).key)
+		c.size -= len(e.Value.(*entry).b)
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package thumbnail renders small previews of PNG, JPEG, and GIF images.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"  // register the GIF decoder
+	_ "image/jpeg" // register the JPEG decoder
+	"image/png"
+	"io"
+)
+
+// ErrUnsupported indicates an image that has no thumbnail.
+var ErrUnsupported = errors.New("unsupported image")
+
+// maxPixels limits the decoded images, which guards against images
+// that are small files but huge in memory. It admits photos of common
+// cameras, which take at most 64 MiB once decoded.
+const maxPixels = 16 << 20
+
+// Render renders a PNG thumbnail of the image that fits in a size×size
+// square. Images smaller than the square keep their size.
+func Render(r io.Reader, size int) ([]byte, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	conf, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if conf.Width <= 0 || conf.Height <= 0 || int64(conf.Width)*int64(conf.Height) > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrUnsupported, conf.Width, conf.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, scale(img, size))
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scale scales the image down to fit in a size×size square, each pixel
+// of the thumbnail averages the pixels that it covers. The source is
+// read row by row in a single pass, and the common image types of the
+// decoders are read from their pixel buffers directly.
+func scale(src image.Image, size int) image.Image {
+	sb := src.Bounds()
+	w, h := sb.Dx(), sb.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	tw, th := size, h*size/w
+	if h > w {
+		tw, th = w*size/h, size
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	// cols maps each source column to the thumbnail column covering it.
+	cols := make([]int, w)
+	for x := range cols {
+		cols[x] = x * tw / w
+	}
+	read, row := rowReader(src), make([]uint8, 4*w)
+	sums := make([]uint64, 5*tw) // premultiplied r, g, b, a and count
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	ty := 0
+	for y := 0; y < h; y++ {
+		if next := y * th / h; next != ty {
+			flush(dst, ty, sums)
+			ty = next
+		}
+		read(sb.Min.Y+y, row)
+		for x, tx := range cols {
+			s := sums[5*tx : 5*tx+5 : 5*tx+5]
+			p := row[4*x : 4*x+4 : 4*x+4]
+			s[0] += uint64(p[0])
+			s[1] += uint64(p[1])
+			s[2] += uint64(p[2])
+			s[3] += uint64(p[3])
+			s[4]++
+		}
+	}
+	flush(dst, ty, sums)
+	return dst
+}
+
+// flush writes the averaged sums to the row y of the thumbnail, and
+// resets the sums for the next row.
+func flush(dst *image.NRGBA, y int, sums []uint64) {
+	pix := dst.Pix[y*dst.Stride:]
+	for x := 0; x < len(sums)/5; x++ {
+		s := sums[5*x : 5*x+5 : 5*x+5]
+		// colors are premultiplied so transparent pixels do not
+		// darken the edges.
+		if a := s[3]; a > 0 {
+			pix[4*x+0] = uint8(s[0] * 0xff / a)
+			pix[4*x+1] = uint8(s[1] * 0xff / a)
+			pix[4*x+2] = uint8(s[2] * 0xff / a)
+			pix[4*x+3] = uint8(a / s[4])
+		}
+		s[0], s[1], s[2], s[3], s[4] = 0, 0, 0, 0, 0
+	}
+}
+
+// rowReader returns a function that reads the row y of the image as
+// premultiplied RGBA.
+func rowReader(src image.Image) func(y int, row []uint8) {
+	x0 := src.Bounds().Min.X
+	switch img := src.(type) {
+	case *image.RGBA:
+		return func(y int, row []uint8) {
+			i := img.PixOffset(x0, y)
+			copy(row, img.Pix[i:i+len(row)])
+		}
+	case *image.NRGBA:
+		return func(y int, row []uint8) {
+			pix := img.Pix[img.PixOffset(x0, y):]
+			for x := 0; x < len(row); x += 4 {
+				a := uint16(pix[x+3])
+				row[x+0] = uint8(uint16(pix[x+0]) * a / 0xff)
+				row[x+1] = uint8(uint16(pix[x+1]) * a / 0xff)
+				row[x+2] = uint8(uint16(pix[x+2]) * a / 0xff)
+				row[x+3] = uint8(a)
+			}
+		}
+	case *image.YCbCr:
+		return func(y int, row []uint8) {
+			for x := 0; x < len(row)/4; x++ {
+				yi, ci := img.YOffset(x0+x, y), img.COffset(x0+x, y)
+				r, g, b := color.YCbCrToRGB(img.Y[yi], img.Cb[ci], img.Cr[ci])
+				row[4*x+0], row[4*x+1], row[4*x+2], row[4*x+3] = r, g, b, 0xff
+			}
+		}
+	case *image.Gray:
+		return func(y int, row []uint8) {
+			pix := img.Pix[img.PixOffset(x0, y):]
+			for x := 0; x < len(row)/4; x++ {
+				v := pix[x]
+				row[4*x+0], row[4*x+1], row[4*x+2], row[4*x+3] = v, v, v, 0xff
+			}
+		}
+	case *image.Paletted:
+		var palette [256][4]uint8
+		for i, c := range img.Palette {
+			r, g, b, a := c.RGBA()
+			palette[i] = [4]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		}
+		return func(y int, row []uint8) {
+			pix := img.Pix[img.PixOffset(x0, y):]
+			for x := 0; x < len(row)/4; x++ {
+				copy(row[4*x:4*x+4], palette[pix[x]][:])
+			}
+		}
+	}
+	return func(y int, row []uint8) {
+		for x := 0; x < len(row)/4; x++ {
+			r, g, b, a := src.At(x0+x, y).RGBA()
+			row[4*x+0], row[4*x+1], row[4*x+2], row[4*x+3] = uint8(r>>8), uint8(g>>8), uint8(b>>8), uint8(a>>8)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package thumbnail_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"changkun.de/x/midgard/internal/thumbnail"
+)
+
+func encode(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRender(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 400, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 400; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+
+	tests := []struct {
+		size, w, h int
+	}{
+		{128, 128, 32},
+		{1000, 400, 100},
+		{1, 1, 1},
+	}
+	for _, tt := range tests {
+		b, err := thumbnail.Render(bytes.NewReader(encode(t, src)), tt.size)
+		if err != nil {
+			t.Fatalf("cannot render: %v", err)
+		}
+		img, err := png.Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("thumbnail is not a png: %v", err)
+		}
+		if s := img.Bounds().Size(); s.X != tt.w || s.Y != tt.h {
+			t.Fatalf("thumbnail of %d is %v, want %dx%d", tt.size, s, tt.w, tt.h)
+		}
+		if r, _, _, a := img.At(0, 0).RGBA(); r>>8 != 255 || a>>8 != 255 {
+			t.Fatalf("unexpected color of the thumbnail: %v", img.At(0, 0))
+		}
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	rect := image.Rect(0, 0, 300, 200)
+	var jpg, gi bytes.Buffer
+	ycbcr := image.NewYCbCr(rect, image.YCbCrSubsampleRatio420)
+	for i := range ycbcr.Y {
+		ycbcr.Y[i] = 0xff
+	}
+	for i := range ycbcr.Cb {
+		ycbcr.Cb[i], ycbcr.Cr[i] = 0x80, 0x80
+	}
+	if err := jpeg.Encode(&jpg, ycbcr, nil); err != nil {
+		t.Fatal(err)
+	}
+	paletted := image.NewPaletted(rect, color.Palette{color.Transparent, color.White})
+	for i := range paletted.Pix {
+		paletted.Pix[i] = uint8(i % 2) // every other pixel is transparent
+	}
+	if err := gif.Encode(&gi, paletted, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, b := range map[string][]byte{"jpeg": jpg.Bytes(), "gif": gi.Bytes()} {
+		b, err := thumbnail.Render(bytes.NewReader(b), 150)
+		if err != nil {
+			t.Fatalf("cannot render %s: %v", name, err)
+		}
+		img, err := png.Decode(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("thumbnail of %s is not a png: %v", name, err)
+		}
+		if s := img.Bounds().Size(); s.X != 150 || s.Y != 100 {
+			t.Fatalf("thumbnail of %s is %v, want 150x100", name, s)
+		}
+		c := color.NRGBAModel.Convert(img.At(75, 50)).(color.NRGBA)
+		if c.R < 0xf0 || c.G < 0xf0 || c.B < 0xf0 {
+			t.Fatalf("thumbnail of %s is not white: %v", name, c)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := thumbnail.NewCache(10)
+	c.Add("a", make([]byte, 4))
+	c.Add("b", make([]byte, 4))
+	c.Get("a")
+	c.Add("c", make([]byte, 4)) // evicts b, the least recently used
+	c.Add("d", make([]byte, 11))
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true, "d": false} {
+		if _, ok := c.Get(key); ok != want {
+			t.Errorf("%s is cached: %v, want %v", key, ok, want)
+		}
+	}
+}
+
+func TestRenderUnsupported(t *testing.T) {
+	_, err := thumbnail.Render(strings.NewReader("<svg></svg>"), 128)
+	if !errors.Is(err, thumbnail.ErrUnsupported) {
+		t.Fatalf("svg is rendered, err: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package token implements scoped API tokens of midgard users.
+// A token is only shown once when it is created, the server only
+// keeps a hash of it.
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"changkun.de/x/midgard/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Scope is a permission granted to a token.
+type Scope string
+
+// All scopes
+const (
+	ScopeClipboardRead  Scope = "clipboard:read"
+	ScopeClipboardWrite Scope = "clipboard:write"
+	ScopeAllocate       Scope = "allocate"
+	ScopeCode2img       Scope = "code2img"
+)
+
+var scopes = []Scope{
+	ScopeClipboardRead,
+	ScopeClipboardWrite,
+	ScopeAllocate,
+	ScopeCode2img,
+}
+
+// Errors
+var (
+	ErrNoScope  = errors.New("a token requires at least one scope")
+	ErrNotFound = errors.New("token does not exist")
+)
+
+// prefix is the prefix of all tokens, it makes leaked tokens easy to find.
+const prefix = "mgt_"
+
+// ParseScopes parses and validates the given scopes.
+func ParseScopes(ss []string) ([]Scope, error) {
+	if len(ss) == 0 {
+		return nil, ErrNoScope
+	}
+	parsed := make([]Scope, 0, len(ss))
+	for _, s := range ss {
+		valid := false
+		for _, scope := range scopes {
+			if Scope(s) == scope {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown scope %q, valid scopes: %v", s, scopes)
+		}
+		parsed = append(parsed, Scope(s))
+	}
+	return parsed, nil
+}
+
+// Token is an API token of a midgard user.
+type Token struct {
+	ID      string    `yaml:"id"`
+	Name    string    `yaml:"name"`
+	User    string    `yaml:"user"`
+	Scopes  []Scope   `yaml:"scopes"`
+	Hash    string    `yaml:"hash"` // sha256 of the token
+	Created time.Time `yaml:"created"`
+}
+
+// Allows reports whether the token is granted the given scope.
+func (t Token) Allows(s Scope) bool {
+	for _, scope := range t.Scopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Store is a persisted set of tokens.
+type Store struct {
+	mu     sync.Mutex
+	path   string
+	tokens []Token
+}
+
+// NewStore creates a token store that persists tokens in the given file.
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read tokens: %w", err)
+	}
+	err = yaml.Unmarshal(b, &s.tokens)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse tokens: %w", err)
+	}
+	return s, nil
+}
+
+// Create creates a new token for the given user and returns the secret
+// token which is not stored anywhere.
+func (s *Store) Create(user, name string, scopes []Scope) (string, Token, error) {
+	if len(scopes) == 0 {
+		return "", Token{}, ErrNoScope
+	}
+	id, err := utils.NewUUIDShort()
+	if err != nil {
+		return "", Token{}, fmt.Errorf("cannot create token id: %w", err)
+	}
+	buf := make([]byte, 32)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return "", Token{}, fmt.Errorf("cannot create token: %w", err)
+	}
+	secret := prefix + hex.EncodeToString(buf)
+
+	t := Token{
+		ID:      id,
+		Name:    name,
+		User:    user,
+		Scopes:  scopes,
+		Hash:    hash(secret),
+		Created: time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = append(s.tokens, t)
+	err = s.persist()
+	if err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return "", Token{}, err
+	}
+	return secret, t, nil
+}
+
+// List returns all tokens of the given user.
+func (s *Store) List(user string) []Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tokens []Token
+	for _, t := range s.tokens {
+		if t.User == user {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// Revoke revokes the token of the given ID that belongs to the given user.
+func (s *Store) Revoke(user, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.tokens {
+		if t.User != user || t.ID != id {
+			continue
+		}
+		tokens := append(s.tokens[:i:i], s.tokens[i+1:]...)
+		old := s.tokens
+		s.tokens = tokens
+		err := s.persist()
+		if err != nil {
+			s.tokens = old
+			return err
+		}
+		return nil
+	}
+	return ErrNotFound
+}
+
+// Lookup finds the token of the given secret.
+func (s *Store) Lookup(secret string) (Token, bool) {
+	if !strings.HasPrefix(secret, prefix) {
+		return Token{}, false
+	}
+	h := hash(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(h)) == 1 {
+			return t, true
+		}
+	}
+	return Token{}, false
+}
+
+func (s *Store) persist() error {
+	b, err := yaml.Marshal(s.tokens)
+	if err != nil {
+		return fmt.Errorf("cannot encode tokens: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(s.path), fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return fmt.Errorf("cannot create token folder: %w", err)
+	}
+	err = os.WriteFile(s.path, b, 0600)
+	if err != nil {
+		return fmt.Errorf("cannot save tokens: %w", err)
+	}
+	return nil
+}
+
+func hash(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
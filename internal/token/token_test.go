@@ -0,0 +1,74 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package token_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"changkun.de/x/midgard/internal/token"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.yml")
+	s, err := token.NewStore(path)
+	if err != nil {
+		t.Fatalf("cannot create store: %v", err)
+	}
+
+	scopes, err := token.ParseScopes([]string{"clipboard:read"})
+	if err != nil {
+		t.Fatalf("cannot parse scopes: %v", err)
+	}
+	secret, tk, err := s.Create("alice", "phone", scopes)
+	if err != nil {
+		t.Fatalf("cannot create token: %v", err)
+	}
+	if tk.Hash == secret {
+		t.Fatalf("token is stored in plain text")
+	}
+
+	// tokens must survive a restart
+	s, err = token.NewStore(path)
+	if err != nil {
+		t.Fatalf("cannot reload store: %v", err)
+	}
+	got, ok := s.Lookup(secret)
+	if !ok || got.ID != tk.ID || got.User != "alice" {
+		t.Fatalf("cannot lookup the created token, got: %+v", got)
+	}
+	if !got.Allows(token.ScopeClipboardRead) || got.Allows(token.ScopeClipboardWrite) {
+		t.Fatalf("unexpected scopes: %v", got.Scopes)
+	}
+	if _, ok := s.Lookup(secret + "x"); ok {
+		t.Fatalf("lookup an invalid token")
+	}
+	if n := len(s.List("alice")); n != 1 {
+		t.Fatalf("unexpected number of tokens: %d", n)
+	}
+	if n := len(s.List("bob")); n != 0 {
+		t.Fatalf("tokens of others are listed: %d", n)
+	}
+
+	if err := s.Revoke("bob", tk.ID); !errors.Is(err, token.ErrNotFound) {
+		t.Fatalf("revoked a token of others: %v", err)
+	}
+	if err := s.Revoke("alice", tk.ID); err != nil {
+		t.Fatalf("cannot revoke token: %v", err)
+	}
+	if _, ok := s.Lookup(secret); ok {
+		t.Fatalf("revoked token is still valid")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	if _, err := token.ParseScopes(nil); !errors.Is(err, token.ErrNoScope) {
+		t.Fatalf("empty scopes are accepted")
+	}
+	if _, err := token.ParseScopes([]string{"allocate", "admin"}); err == nil {
+		t.Fatalf("unknown scope is accepted")
+	}
+}
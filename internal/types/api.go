@@ -5,15 +5,27 @@
 package types
 
 import (
+	"time"
+
 	"changkun.de/x/midgard/internal/config"
 )
 
 // Endpoints
 var (
-	EndpointClipboard   = config.Get().Domain + "/midgard/api/v1/clipboard"
-	EndpointAllocateURL = config.Get().Domain + "/midgard/api/v1/allocate"
-	EndpointCode2Image  = config.Get().Domain + "/midgard/api/v1/code2img"
-	EndpointSubscribe   = config.Get().Domain + "/midgard/api/v1/ws"
+	EndpointClipboard        = config.Get().Domain + "/midgard/api/v1/clipboard"
+	EndpointClipboardHistory = config.Get().Domain + "/midgard/api/v1/clipboard/history"
+	EndpointClipboardRestore = config.Get().Domain + "/midgard/api/v1/clipboard/restore"
+	EndpointAllocateURL      = config.Get().Domain + "/midgard/api/v1/allocate"
+	EndpointCode2Image       = config.Get().Domain + "/midgard/api/v1/code2img"
+	EndpointSubscribe        = config.Get().Domain + "/midgard/api/v1/ws"
+	EndpointTokens           = config.Get().Domain + "/midgard/api/v1/tokens"
+	EndpointBlocklist        = config.Get().Domain + "/midgard/api/v1/blocklist"
+	EndpointResources        = config.Get().Domain + "/midgard/api/v1/resources"
+	EndpointResourcesMove    = config.Get().Domain + "/midgard/api/v1/resources/move"
+	EndpointUploads          = config.Get().Domain + "/midgard/api/v1/uploads"
+	EndpointStatus           = config.Get().Domain + "/midgard/api/v1/status"
+	EndpointQuota            = config.Get().Domain + "/midgard/api/v1/quota"
+	EndpointCode             = config.Get().Domain + "/midgard/api/v1/code"
 )
 
 // PingInput is the input for /ping
@@ -26,9 +38,28 @@ type PingOutput struct {
 	BuildTime string `json:"build_time"`
 }
 
+// StatusOutput is the output of the server status.
+type StatusOutput struct {
+	Version string       `json:"version"`
+	Backup  BackupStatus `json:"backup"`
+	Message string       `json:"msg"`
+}
+
+// BackupStatus is the status of the backups of the server.
+type BackupStatus struct {
+	Enabled     bool      `json:"enabled"`
+	Strategy    string    `json:"strategy"`
+	LastSuccess time.Time `json:"last_success"`
+	LastFailure time.Time `json:"last_failure"`
+	Revision    string    `json:"revision"` // of the last successful backup
+	Error       string    `json:"error"`    // of the last failed backup
+	Failures    int       `json:"failures"` // consecutive failures
+}
+
 // GetFromUniversalClipboardInput is the standard input format of
 // the universal clipboard put request.
 type GetFromUniversalClipboardInput struct {
+	Channel string `form:"channel"`
 }
 
 // GetFromUniversalClipboardOutput is the standard output format of
@@ -40,6 +71,7 @@ type GetFromUniversalClipboardOutput ClipboardData
 type PutToUniversalClipboardInput struct {
 	ClipboardData
 	DaemonID string `json:"daemon_id"`
+	Channel  string `json:"channel,omitempty"`
 }
 
 // PutToUniversalClipboardOutput is the standard output format of
@@ -48,6 +80,49 @@ type PutToUniversalClipboardOutput struct {
 	Message string `json:"msg"`
 }
 
+// GetClipboardHistoryInput is the query format of the universal
+// clipboard history request. Since and Until are RFC3339 timestamps.
+// If ID is given, only the entry of the ID is returned.
+type GetClipboardHistoryInput struct {
+	Channel string    `form:"channel"`
+	ID      uint64    `form:"id"`
+	Offset  int       `form:"offset"`
+	Limit   int       `form:"limit"`
+	Since   time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until   time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// ClipboardHistoryEntry is an entry of the universal clipboard history.
+type ClipboardHistoryEntry struct {
+	ClipboardData
+	ID       uint64    `json:"id"`
+	Time     time.Time `json:"time"`
+	DaemonID string    `json:"daemon_id"`
+	Hash     string    `json:"hash"`
+}
+
+// GetClipboardHistoryOutput is the standard output format of
+// the universal clipboard history request, newest entries first.
+type GetClipboardHistoryOutput struct {
+	Total   int                     `json:"total"`
+	Entries []ClipboardHistoryEntry `json:"entries"`
+	Message string                  `json:"msg"`
+}
+
+// RestoreClipboardHistoryInput is the standard input format of
+// the universal clipboard history restore request.
+type RestoreClipboardHistoryInput struct {
+	ID       uint64 `json:"id"`
+	DaemonID string `json:"daemon_id"`
+	Channel  string `json:"channel,omitempty"`
+}
+
+// RestoreClipboardHistoryOutput is the standard output format of
+// the universal clipboard history restore request.
+type RestoreClipboardHistoryOutput struct {
+	Message string `json:"msg"`
+}
+
 // SourceType is the source type for URL allocation.
 //
 // Note: We use string for the data type because this is better
@@ -66,6 +141,17 @@ type AllocateURLInput struct {
 	Source SourceType `json:"source"`
 	URI    string     `json:"uri"`
 	Data   string     `json:"data"`
+	Type   MIME       `json:"type"` // optional, MIME type of attached data
+
+	// TTL is an optional duration, e.g. 1h, after which the resource
+	// is removed. Such resources are not backed up.
+	TTL string `json:"ttl"`
+	// MaxViews is an optional number of allowed views, the resource is
+	// removed after the last view. Such resources are not backed up.
+	MaxViews int `json:"max_views"`
+	// Password is an optional password that visitors of the resource
+	// need to enter, it is only stored as a salted hash.
+	Password string `json:"password"`
 }
 
 // AllocateURLOutput ...
@@ -85,3 +171,183 @@ type Code2ImgOutput struct {
 	Image   string `json:"img"`
 	Message string `json:"msg"`
 }
+
+// ListCodeInput is the query format of the code list request. Pages
+// start from 1, and a query lists the codes that contain all its words.
+type ListCodeInput struct {
+	Query string `form:"q"`
+	Page  int    `form:"page"`
+	Size  int    `form:"size"`
+}
+
+// CodeInfo describes a code of code2img.
+type CodeInfo struct {
+	ID    string    `json:"id"`
+	Time  time.Time `json:"time"`
+	Code  string    `json:"code"`
+	Text  string    `json:"text"`
+	Image string    `json:"img"`
+}
+
+// ListCodeOutput is the standard output format of the code list request.
+type ListCodeOutput struct {
+	Codes   []CodeInfo `json:"codes"`
+	Total   int        `json:"total"`
+	Page    int        `json:"page"`
+	Size    int        `json:"size"`
+	Message string     `json:"msg"`
+}
+
+// DeleteCodeOutput is the standard output format of the code delete
+// request.
+type DeleteCodeOutput struct {
+	Message string `json:"msg"`
+}
+
+// CreateTokenInput is the standard input format of the token create request.
+type CreateTokenInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateTokenOutput is the standard output format of the token create
+// request. The token is only returned once.
+type CreateTokenOutput struct {
+	ID      string `json:"id"`
+	Token   string `json:"token"`
+	Message string `json:"msg"`
+}
+
+// TokenInfo describes an API token without revealing the token.
+type TokenInfo struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Scopes  []string  `json:"scopes"`
+	Created time.Time `json:"created"`
+}
+
+// ListTokensOutput is the standard output format of the token list request.
+type ListTokensOutput struct {
+	Tokens  []TokenInfo `json:"tokens"`
+	Message string      `json:"msg"`
+}
+
+// RevokeTokenOutput is the standard output format of the token revoke request.
+type RevokeTokenOutput struct {
+	Message string `json:"msg"`
+}
+
+// BlockedIP is an IP that failed to authenticate.
+type BlockedIP struct {
+	IP           string    `json:"ip"`
+	Failures     int       `json:"failures"`
+	LastFailure  time.Time `json:"last_failure"`
+	BlockedUntil time.Time `json:"blocked_until,omitempty"` // zero if not blocked
+}
+
+// ListBlocklistOutput is the standard output format of the blocklist
+// list request.
+type ListBlocklistOutput struct {
+	Entries []BlockedIP `json:"entries"`
+	Message string      `json:"msg"`
+}
+
+// ClearBlocklistOutput is the standard output format of the blocklist
+// clear request.
+type ClearBlocklistOutput struct {
+	Message string `json:"msg"`
+}
+
+// ResourceInfo describes an allocated resource or a folder.
+type ResourceInfo struct {
+	Path      string    `json:"path"`
+	URL       string    `json:"url"`
+	Dir       bool      `json:"dir"`
+	Type      string    `json:"type,omitempty"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Expires   time.Time `json:"expires,omitempty"`
+	MaxViews  int       `json:"max_views,omitempty"`
+	Views     int       `json:"views,omitempty"`
+	Protected bool      `json:"protected,omitempty"`
+	Thumbnail string    `json:"thumbnail,omitempty"` // of images in index pages
+}
+
+// ListResourcesInput is the query format of the resource list request.
+// Path is a folder in the namespace of the user, the root by default.
+type ListResourcesInput struct {
+	Path string `form:"path"`
+}
+
+// ListResourcesOutput is the standard output format of the resource
+// list request.
+type ListResourcesOutput struct {
+	Resources []ResourceInfo `json:"resources"`
+	Message   string         `json:"msg"`
+}
+
+// DeleteResourceInput is the query format of the resource delete
+// request. Only files and empty folders can be deleted.
+type DeleteResourceInput struct {
+	Path string `form:"path"`
+}
+
+// DeleteResourceOutput is the standard output format of the resource
+// delete request.
+type DeleteResourceOutput struct {
+	Message string `json:"msg"`
+}
+
+// MoveResourceInput is the standard input format of the resource move
+// request.
+type MoveResourceInput struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MoveResourceOutput is the standard output format of the resource
+// move request.
+type MoveResourceOutput struct {
+	URL     string `json:"url"`
+	Message string `json:"msg"`
+}
+
+// HeaderUploadOffset is the header that carries the offset of an
+// upload chunk.
+const HeaderUploadOffset = "Upload-Offset"
+
+// CreateUploadInput is the standard input format of the upload create
+// request. The allocation of the uploaded data happens once all Size
+// bytes are uploaded, Data and Source of the allocation are ignored.
+type CreateUploadInput struct {
+	AllocateURLInput
+	Size int64 `json:"size"`
+}
+
+// UploadOutput is the standard output format of the upload requests.
+// Offset is the number of received bytes. URL is set once the upload
+// is completed.
+type UploadOutput struct {
+	ID      string `json:"id"`
+	Offset  int64  `json:"offset"`
+	URL     string `json:"url,omitempty"`
+	Message string `json:"msg"`
+}
+
+// QuotaUsage is an amount of storage, as a limit a zero field is
+// unlimited.
+type QuotaUsage struct {
+	Bytes int64 `json:"bytes"`
+	Files int   `json:"files"`
+}
+
+// QuotaOutput is the output of the storage quota of a user.
+type QuotaOutput struct {
+	User       string     `json:"user"`
+	Usage      QuotaUsage `json:"usage"`
+	Limit      QuotaUsage `json:"limit"`
+	Total      QuotaUsage `json:"total"`
+	TotalLimit QuotaUsage `json:"total_limit"`
+	MaxUpload  int64      `json:"max_upload"`
+	Message    string     `json:"msg"`
+}
@@ -4,10 +4,75 @@
 
 package types
 
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
 // ClipboardData is a clipboard data
 type ClipboardData struct {
 	Type MIME   `json:"type"`
-	Data string `json:"data"` // base64 encode if type is an image data
+	Data string `json:"data"` // base64 encode if type is not plain text
+
+	// Alternatives are other representations of the same clipboard
+	// content, e.g. the HTML version of a copied text. Clients that
+	// do not understand alternatives only see the Type and Data.
+	Alternatives []ClipboardData `json:"alternatives,omitempty"`
+}
+
+// Representation is a flavor of clipboard content in raw bytes.
+type Representation struct {
+	Type MIME
+	Data []byte
+}
+
+// NewClipboardData creates a clipboard data of the given MIME type
+// and encodes the given raw data accordingly.
+func NewClipboardData(t MIME, buf []byte) ClipboardData {
+	if t.IsText() {
+		return ClipboardData{Type: t, Data: string(buf)}
+	}
+	return ClipboardData{Type: t, Data: base64.StdEncoding.EncodeToString(buf)}
+}
+
+// EncodeClipboardData creates a clipboard data from the given
+// representations. The first one is the primary representation
+// and the rest are alternatives.
+func EncodeClipboardData(reps ...Representation) ClipboardData {
+	if len(reps) == 0 {
+		return NewClipboardData(MIMEPlainText, nil)
+	}
+	d := NewClipboardData(reps[0].Type, reps[0].Data)
+	for _, r := range reps[1:] {
+		d.Alternatives = append(d.Alternatives, NewClipboardData(r.Type, r.Data))
+	}
+	return d
+}
+
+// Bytes decodes and returns the raw data of the primary representation.
+func (d ClipboardData) Bytes() ([]byte, error) {
+	if d.Type.IsText() {
+		return []byte(d.Data), nil
+	}
+	return base64.StdEncoding.DecodeString(d.Data)
+}
+
+// Decode decodes all representations of the clipboard data, the
+// primary representation comes first.
+func (d ClipboardData) Decode() ([]Representation, error) {
+	all := append([]ClipboardData{d}, d.Alternatives...)
+	reps := make([]Representation, 0, len(all))
+	for _, dd := range all {
+		buf, err := dd.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode %s data: %w", dd.Type, err)
+		}
+		reps = append(reps, Representation{Type: dd.Type, Data: buf})
+	}
+	return reps, nil
 }
 
 // MIME indicates clipboard data type
@@ -21,4 +86,132 @@ const (
 	MIMEPlainText MIME = "text"
 	// MIMEImagePNG indicates image/png data type
 	MIMEImagePNG = "image/png"
+	// MIMEImageJPEG indicates image/jpeg data type
+	MIMEImageJPEG = "image/jpeg"
+	// MIMEHTML indicates text/html data type
+	MIMEHTML = "text/html"
+	// MIMERTF indicates text/rtf data type
+	MIMERTF = "text/rtf"
+	// MIMEURIList indicates text/uri-list data type, e.g. copied files
+	MIMEURIList = "text/uri-list"
+	// MIMEEncrypted indicates end-to-end encrypted clipboard data that
+	// can only be read by daemons that share the same key.
+	MIMEEncrypted = "application/vnd.midgard.encrypted"
 )
+
+// IsText reports whether the data of the MIME type is transferred as is.
+// Data of any other MIME type is an opaque binary payload and transferred
+// as base64 encoded string, see ClipboardData.
+func (m MIME) IsText() bool {
+	return m == MIMEPlainText
+}
+
+// IsImage reports whether the MIME type is an image type.
+func (m MIME) IsImage() bool {
+	return strings.HasPrefix(string(m), "image/")
+}
+
+var extensions = map[MIME]string{
+	MIMEPlainText: ".txt",
+	MIMEImagePNG:  ".png",
+	MIMEImageJPEG: ".jpg",
+	MIMEHTML:      ".html",
+	MIMERTF:       ".rtf",
+	MIMEURIList:   ".txt",
+
+	// common types of http.DetectContentType that are ambiguous or
+	// missing in system MIME tables.
+	"text/plain":                   ".txt",
+	"text/xml":                     ".xml",
+	"image/gif":                    ".gif",
+	"image/webp":                   ".webp",
+	"image/bmp":                    ".bmp",
+	"image/x-icon":                 ".ico",
+	"audio/mpeg":                   ".mp3",
+	"audio/wave":                   ".wav",
+	"audio/aiff":                   ".aiff",
+	"audio/midi":                   ".mid",
+	"application/ogg":              ".ogg",
+	"video/mp4":                    ".mp4",
+	"video/webm":                   ".webm",
+	"video/avi":                    ".avi",
+	"application/pdf":              ".pdf",
+	"application/postscript":       ".ps",
+	"application/zip":              ".zip",
+	"application/x-gzip":           ".gz",
+	"application/x-rar-compressed": ".rar",
+	"application/wasm":             ".wasm",
+	"font/woff":                    ".woff",
+	"font/woff2":                   ".woff2",
+	"font/ttf":                     ".ttf",
+	"font/otf":                     ".otf",
+	"application/octet-stream":     ".bin",
+}
+
+// Ext returns the file extension of the MIME type, it falls back to
+// ".bin" if the MIME type is unknown.
+func (m MIME) Ext() string {
+	if ext, ok := extensions[m]; ok {
+		return ext
+	}
+	exts, err := mime.ExtensionsByType(string(m))
+	if err != nil || len(exts) == 0 {
+		return ".bin"
+	}
+	return exts[0]
+}
+
+// DetectMIME detects the MIME type of the given data by its content,
+// see http.DetectContentType. Plain text is detected as MIMEPlainText,
+// and unknown data as application/octet-stream.
+func DetectMIME(data []byte) MIME {
+	t, _, err := mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if t == "text/plain" {
+		return MIMEPlainText
+	}
+	return MIME(t)
+}
+
+// ContentType returns the value of the Content-Type header to serve
+// data of the MIME type. Text is always served as UTF-8.
+func (m MIME) ContentType() string {
+	switch {
+	case m == MIMEPlainText:
+		return "text/plain; charset=utf-8"
+	case strings.HasPrefix(string(m), "text/"):
+		return string(m) + "; charset=utf-8"
+	default:
+		return string(m)
+	}
+}
+
+// active are MIME types that browsers render as documents, which may
+// run scripts in the origin of midgard.
+var active = map[MIME]bool{
+	"text/html":             true,
+	"text/xml":              true,
+	"application/xml":       true,
+	"application/xhtml+xml": true,
+	"image/svg+xml":         true,
+}
+
+// Inline reports whether browsers display data of the MIME type rather
+// than download it. Active documents, such as HTML or SVG, are never
+// displayed.
+func (m MIME) Inline() bool {
+	if active[m] {
+		return false
+	}
+	if m == MIMEPlainText || m == "application/pdf" {
+		return true
+	}
+	for _, prefix := range []string{"text/", "image/", "audio/", "video/"} {
+		if strings.HasPrefix(string(m), prefix) {
+			return true
+		}
+	}
+	return false
+}
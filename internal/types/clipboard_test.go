@@ -0,0 +1,126 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package types_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"changkun.de/x/midgard/internal/types"
+)
+
+func TestClipboardDataRoundTrip(t *testing.T) {
+	tests := []struct {
+		typ types.MIME
+		buf []byte
+	}{
+		{types.MIMEPlainText, []byte("hello midgard")},
+		{types.MIMEImagePNG, []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}},
+		{types.MIMEHTML, []byte("<b>hello</b>")},
+		{types.MIME("application/x-unknown"), []byte{0x00, 0x01, 0xfe}},
+	}
+
+	for _, tt := range tests {
+		b, err := json.Marshal(types.NewClipboardData(tt.typ, tt.buf))
+		if err != nil {
+			t.Fatalf("failed to encode %v: %v", tt.typ, err)
+		}
+		var d types.ClipboardData
+		if err := json.Unmarshal(b, &d); err != nil {
+			t.Fatalf("failed to decode %v: %v", tt.typ, err)
+		}
+		got, err := d.Bytes()
+		if err != nil {
+			t.Fatalf("failed to read bytes of %v: %v", tt.typ, err)
+		}
+		if d.Type != tt.typ || !bytes.Equal(got, tt.buf) {
+			t.Fatalf("inconsistent round trip of %v, got: %v", tt.typ, got)
+		}
+	}
+}
+
+func TestClipboardDataAlternatives(t *testing.T) {
+	reps := []types.Representation{
+		{Type: types.MIMEPlainText, Data: []byte("hello")},
+		{Type: types.MIMEHTML, Data: []byte("<b>hello</b>")},
+		{Type: types.MIMEImagePNG, Data: []byte{0x89, 'P', 'N', 'G'}},
+	}
+
+	b, err := json.Marshal(types.EncodeClipboardData(reps...))
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	var d types.ClipboardData
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	// old clients only look at the primary representation
+	if d.Type != types.MIMEPlainText || d.Data != "hello" {
+		t.Fatalf("unexpected primary representation: %v, %v", d.Type, d.Data)
+	}
+
+	got, err := d.Decode()
+	if err != nil {
+		t.Fatalf("failed to decode representations: %v", err)
+	}
+	if len(got) != len(reps) {
+		t.Fatalf("unexpected number of representations, want %d, got %d", len(reps), len(got))
+	}
+	for i := range reps {
+		if got[i].Type != reps[i].Type || !bytes.Equal(got[i].Data, reps[i].Data) {
+			t.Fatalf("inconsistent representation %d, got: %v", i, got[i].Type)
+		}
+	}
+}
+
+func TestMIMEExt(t *testing.T) {
+	tests := map[types.MIME]string{
+		types.MIMEPlainText:            ".txt",
+		types.MIMEImagePNG:             ".png",
+		types.MIMEImageJPEG:            ".jpg",
+		types.MIMEHTML:                 ".html",
+		types.MIME("application/pdf"):  ".pdf",
+		types.MIME("application/x-42"): ".bin",
+	}
+	for typ, want := range tests {
+		if got := typ.Ext(); got != want {
+			t.Fatalf("unexpected extension of %v, want %v, got %v", typ, want, got)
+		}
+	}
+}
+
+func TestDetectMIME(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		want   types.MIME
+		ctype  string
+		inline bool
+	}{
+		{[]byte("hello, midgard"), types.MIMEPlainText, "text/plain; charset=utf-8", true},
+		{[]byte("\x89PNG\x0D\x0A\x1A\x0A"), types.MIMEImagePNG, "image/png", true},
+		{[]byte("%PDF-1.4"), "application/pdf", "application/pdf", true},
+		{[]byte("PK\x03\x04"), "application/zip", "application/zip", false},
+		{[]byte{0, 1, 2, 3}, "application/octet-stream", "application/octet-stream", false},
+		{[]byte("<!DOCTYPE html><script>alert(1)</script>"), "text/html", "text/html; charset=utf-8", false},
+		{[]byte("<?xml version=\"1.0\"?><svg/>"), "text/xml", "text/xml; charset=utf-8", false},
+	}
+	for _, tt := range tests {
+		got := types.DetectMIME(tt.data)
+		if got != tt.want {
+			t.Fatalf("unexpected type of %q, want %v, got %v", tt.data, tt.want, got)
+		}
+		if ctype := got.ContentType(); ctype != tt.ctype {
+			t.Fatalf("unexpected content type of %v, want %v, got %v", got, tt.ctype, ctype)
+		}
+		if got.Inline() != tt.inline {
+			t.Fatalf("unexpected disposition of %v, want inline: %v", got, tt.inline)
+		}
+	}
+	if ext := types.DetectMIME([]byte{0, 1, 2, 3}).Ext(); ext != ".bin" {
+		t.Fatalf("unexpected extension of unknown data, want .bin, got %v", ext)
+	}
+}
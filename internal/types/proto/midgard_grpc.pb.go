@@ -20,8 +20,14 @@ const _ = grpc.SupportPackageIsVersion7
 type MidgardClient interface {
 	Ping(ctx context.Context, in *PingInput, opts ...grpc.CallOption) (*PingOutput, error)
 	AllocateURL(ctx context.Context, in *AllocateURLInput, opts ...grpc.CallOption) (*AllocateURLOutput, error)
+	Upload(ctx context.Context, opts ...grpc.CallOption) (Midgard_UploadClient, error)
 	CodeToImage(ctx context.Context, in *CodeToImageInput, opts ...grpc.CallOption) (*CodeToImageOutput, error)
 	ListDaemons(ctx context.Context, in *ListDaemonsInput, opts ...grpc.CallOption) (*ListDaemonsOutput, error)
+	ListHistory(ctx context.Context, in *ListHistoryInput, opts ...grpc.CallOption) (*ListHistoryOutput, error)
+	RestoreHistory(ctx context.Context, in *RestoreHistoryInput, opts ...grpc.CallOption) (*RestoreHistoryOutput, error)
+	ListResources(ctx context.Context, in *ListResourcesInput, opts ...grpc.CallOption) (*ListResourcesOutput, error)
+	DeleteResource(ctx context.Context, in *DeleteResourceInput, opts ...grpc.CallOption) (*DeleteResourceOutput, error)
+	MoveResource(ctx context.Context, in *MoveResourceInput, opts ...grpc.CallOption) (*MoveResourceOutput, error)
 }
 
 type midgardClient struct {
@@ -50,6 +56,37 @@ func (c *midgardClient) AllocateURL(ctx context.Context, in *AllocateURLInput, o
 	return out, nil
 }
 
+func (c *midgardClient) Upload(ctx context.Context, opts ...grpc.CallOption) (Midgard_UploadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Midgard_ServiceDesc.Streams[0], "/proto.Midgard/Upload", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &midgardUploadClient{stream}
+	return x, nil
+}
+
+type Midgard_UploadClient interface {
+	Send(*UploadInput) error
+	Recv() (*UploadOutput, error)
+	grpc.ClientStream
+}
+
+type midgardUploadClient struct {
+	grpc.ClientStream
+}
+
+func (x *midgardUploadClient) Send(m *UploadInput) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *midgardUploadClient) Recv() (*UploadOutput, error) {
+	m := new(UploadOutput)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *midgardClient) CodeToImage(ctx context.Context, in *CodeToImageInput, opts ...grpc.CallOption) (*CodeToImageOutput, error) {
 	out := new(CodeToImageOutput)
 	err := c.cc.Invoke(ctx, "/proto.Midgard/CodeToImage", in, out, opts...)
@@ -68,14 +105,65 @@ func (c *midgardClient) ListDaemons(ctx context.Context, in *ListDaemonsInput, o
 	return out, nil
 }
 
+func (c *midgardClient) ListHistory(ctx context.Context, in *ListHistoryInput, opts ...grpc.CallOption) (*ListHistoryOutput, error) {
+	out := new(ListHistoryOutput)
+	err := c.cc.Invoke(ctx, "/proto.Midgard/ListHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *midgardClient) RestoreHistory(ctx context.Context, in *RestoreHistoryInput, opts ...grpc.CallOption) (*RestoreHistoryOutput, error) {
+	out := new(RestoreHistoryOutput)
+	err := c.cc.Invoke(ctx, "/proto.Midgard/RestoreHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *midgardClient) ListResources(ctx context.Context, in *ListResourcesInput, opts ...grpc.CallOption) (*ListResourcesOutput, error) {
+	out := new(ListResourcesOutput)
+	err := c.cc.Invoke(ctx, "/proto.Midgard/ListResources", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *midgardClient) DeleteResource(ctx context.Context, in *DeleteResourceInput, opts ...grpc.CallOption) (*DeleteResourceOutput, error) {
+	out := new(DeleteResourceOutput)
+	err := c.cc.Invoke(ctx, "/proto.Midgard/DeleteResource", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *midgardClient) MoveResource(ctx context.Context, in *MoveResourceInput, opts ...grpc.CallOption) (*MoveResourceOutput, error) {
+	out := new(MoveResourceOutput)
+	err := c.cc.Invoke(ctx, "/proto.Midgard/MoveResource", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MidgardServer is the server API for Midgard service.
 // All implementations must embed UnimplementedMidgardServer
 // for forward compatibility
 type MidgardServer interface {
 	Ping(context.Context, *PingInput) (*PingOutput, error)
 	AllocateURL(context.Context, *AllocateURLInput) (*AllocateURLOutput, error)
+	Upload(Midgard_UploadServer) error
 	CodeToImage(context.Context, *CodeToImageInput) (*CodeToImageOutput, error)
 	ListDaemons(context.Context, *ListDaemonsInput) (*ListDaemonsOutput, error)
+	ListHistory(context.Context, *ListHistoryInput) (*ListHistoryOutput, error)
+	RestoreHistory(context.Context, *RestoreHistoryInput) (*RestoreHistoryOutput, error)
+	ListResources(context.Context, *ListResourcesInput) (*ListResourcesOutput, error)
+	DeleteResource(context.Context, *DeleteResourceInput) (*DeleteResourceOutput, error)
+	MoveResource(context.Context, *MoveResourceInput) (*MoveResourceOutput, error)
 	mustEmbedUnimplementedMidgardServer()
 }
 
@@ -89,12 +177,30 @@ func (UnimplementedMidgardServer) Ping(context.Context, *PingInput) (*PingOutput
 func (UnimplementedMidgardServer) AllocateURL(context.Context, *AllocateURLInput) (*AllocateURLOutput, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AllocateURL not implemented")
 }
+func (UnimplementedMidgardServer) Upload(Midgard_UploadServer) error {
+	return status.Errorf(codes.Unimplemented, "method Upload not implemented")
+}
 func (UnimplementedMidgardServer) CodeToImage(context.Context, *CodeToImageInput) (*CodeToImageOutput, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CodeToImage not implemented")
 }
 func (UnimplementedMidgardServer) ListDaemons(context.Context, *ListDaemonsInput) (*ListDaemonsOutput, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListDaemons not implemented")
 }
+func (UnimplementedMidgardServer) ListHistory(context.Context, *ListHistoryInput) (*ListHistoryOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHistory not implemented")
+}
+func (UnimplementedMidgardServer) RestoreHistory(context.Context, *RestoreHistoryInput) (*RestoreHistoryOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreHistory not implemented")
+}
+func (UnimplementedMidgardServer) ListResources(context.Context, *ListResourcesInput) (*ListResourcesOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListResources not implemented")
+}
+func (UnimplementedMidgardServer) DeleteResource(context.Context, *DeleteResourceInput) (*DeleteResourceOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteResource not implemented")
+}
+func (UnimplementedMidgardServer) MoveResource(context.Context, *MoveResourceInput) (*MoveResourceOutput, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveResource not implemented")
+}
 func (UnimplementedMidgardServer) mustEmbedUnimplementedMidgardServer() {}
 
 // UnsafeMidgardServer may be embedded to opt out of forward compatibility for this service.
@@ -144,6 +250,32 @@ func _Midgard_AllocateURL_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Midgard_Upload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MidgardServer).Upload(&midgardUploadServer{stream})
+}
+
+type Midgard_UploadServer interface {
+	Send(*UploadOutput) error
+	Recv() (*UploadInput, error)
+	grpc.ServerStream
+}
+
+type midgardUploadServer struct {
+	grpc.ServerStream
+}
+
+func (x *midgardUploadServer) Send(m *UploadOutput) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *midgardUploadServer) Recv() (*UploadInput, error) {
+	m := new(UploadInput)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _Midgard_CodeToImage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(CodeToImageInput)
 	if err := dec(in); err != nil {
@@ -180,6 +312,96 @@ func _Midgard_ListDaemons_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Midgard_ListHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHistoryInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MidgardServer).ListHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Midgard/ListHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MidgardServer).ListHistory(ctx, req.(*ListHistoryInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Midgard_RestoreHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreHistoryInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MidgardServer).RestoreHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Midgard/RestoreHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MidgardServer).RestoreHistory(ctx, req.(*RestoreHistoryInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Midgard_ListResources_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListResourcesInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MidgardServer).ListResources(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Midgard/ListResources",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MidgardServer).ListResources(ctx, req.(*ListResourcesInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Midgard_DeleteResource_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteResourceInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MidgardServer).DeleteResource(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Midgard/DeleteResource",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MidgardServer).DeleteResource(ctx, req.(*DeleteResourceInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Midgard_MoveResource_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveResourceInput)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MidgardServer).MoveResource(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.Midgard/MoveResource",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MidgardServer).MoveResource(ctx, req.(*MoveResourceInput))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Midgard_ServiceDesc is the grpc.ServiceDesc for Midgard service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -203,7 +425,34 @@ var Midgard_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListDaemons",
 			Handler:    _Midgard_ListDaemons_Handler,
 		},
+		{
+			MethodName: "ListHistory",
+			Handler:    _Midgard_ListHistory_Handler,
+		},
+		{
+			MethodName: "RestoreHistory",
+			Handler:    _Midgard_RestoreHistory_Handler,
+		},
+		{
+			MethodName: "ListResources",
+			Handler:    _Midgard_ListResources_Handler,
+		},
+		{
+			MethodName: "DeleteResource",
+			Handler:    _Midgard_DeleteResource_Handler,
+		},
+		{
+			MethodName: "MoveResource",
+			Handler:    _Midgard_MoveResource_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Upload",
+			Handler:       _Midgard_Upload_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "midgard.proto",
 }
@@ -34,10 +34,17 @@ type WebsocketAction string
 type WebsocketMessage struct {
 	Action  WebsocketAction `json:"action"`
 	UserID  string          `json:"user_id"`
+	Channel string          `json:"channel,omitempty"` // empty for the private clipboard
 	Message string          `json:"msg"`
 	Data    []byte          `json:"data"` // action dependent data, json format
 }
 
+// HandshakeData is the data of the register handshake. The daemon sends
+// the channels it wants to join, and the server replies the joined ones.
+type HandshakeData struct {
+	Channels []string `json:"channels"`
+}
+
 // Encode encodes a websocket message
 func (m *WebsocketMessage) Encode() []byte {
 	b, _ := json.Marshal(m)
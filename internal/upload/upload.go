@@ -0,0 +1,285 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+// Package upload implements resumable upload sessions. The data of a
+// session is appended chunk by chunk, and a session survives restarts
+// so that an interrupted upload can continue from its current offset.
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Errors
+var (
+	ErrNotFound       = errors.New("upload session does not exist")
+	ErrOffsetMismatch = errors.New("upload offset does not match")
+	ErrTooLarge       = errors.New("upload exceeds the declared size")
+	ErrCompleting     = errors.New("upload is being completed")
+)
+
+// Session is an upload session.
+type Session struct {
+	ID      string    `yaml:"id"`
+	User    string    `yaml:"user"`
+	Size    int64     `yaml:"size"`
+	Created time.Time `yaml:"created"`
+	// Updated is the time of the last append, it is not persisted
+	// but taken from the data file.
+	Updated time.Time `yaml:"-"`
+	// Alloc is the allocation request of the uploaded data, its Data
+	// is always empty.
+	Alloc types.AllocateURLInput `yaml:"alloc"`
+}
+
+// Store stores upload sessions in a folder. Each session has a data
+// file <id> and a session file <id>.yml.
+type Store struct {
+	dir        string
+	locks      sync.Map // map[string]*sync.Mutex, serializes changes of a session
+	completing sync.Map // map[string]bool, sessions that are being completed
+}
+
+// NewStore creates a store in the given folder.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Create creates a new upload session of the given size.
+func (s *Store) Create(user string, size int64, alloc types.AllocateURLInput) (Session, error) {
+	id, err := utils.NewUUIDShort()
+	if err != nil {
+		return Session{}, fmt.Errorf("cannot create upload id: %w", err)
+	}
+	alloc.Data = ""
+	ss := Session{
+		ID:      id,
+		User:    user,
+		Size:    size,
+		Created: time.Now().UTC(),
+		Alloc:   alloc,
+	}
+	b, err := yaml.Marshal(ss)
+	if err != nil {
+		return Session{}, fmt.Errorf("cannot encode upload session: %w", err)
+	}
+	err = os.MkdirAll(s.dir, fs.ModeDir|fs.ModePerm)
+	if err != nil {
+		return Session{}, fmt.Errorf("cannot create upload folder: %w", err)
+	}
+	err = os.WriteFile(s.Path(id), nil, 0600)
+	if err != nil {
+		return Session{}, fmt.Errorf("cannot create upload file: %w", err)
+	}
+	err = os.WriteFile(s.Path(id)+".yml", b, 0600)
+	if err != nil {
+		os.Remove(s.Path(id))
+		return Session{}, fmt.Errorf("cannot save upload session: %w", err)
+	}
+	return ss, nil
+}
+
+// Get returns the upload session of the given ID and its current offset.
+func (s *Store) Get(id string) (Session, int64, error) {
+	if !valid(id) {
+		return Session{}, 0, ErrNotFound
+	}
+	b, err := os.ReadFile(s.Path(id) + ".yml")
+	if errors.Is(err, fs.ErrNotExist) {
+		return Session{}, 0, ErrNotFound
+	}
+	if err != nil {
+		return Session{}, 0, fmt.Errorf("cannot read upload session: %w", err)
+	}
+	var ss Session
+	err = yaml.Unmarshal(b, &ss)
+	if err != nil {
+		return Session{}, 0, fmt.Errorf("cannot parse upload session: %w", err)
+	}
+	info, err := os.Stat(s.Path(id))
+	if err != nil {
+		return Session{}, 0, ErrNotFound
+	}
+	ss.Updated = info.ModTime().UTC()
+	return ss, info.Size(), nil
+}
+
+// Append appends the data of the given reader to the upload session at
+// the given offset, and returns the new offset. The offset must be the
+// current offset of the session, which is returned with ErrOffsetMismatch
+// otherwise. A failed append can be resumed from the returned offset.
+// Sessions that are being completed cannot be appended to.
+func (s *Store) Append(id string, offset int64, r io.Reader) (int64, error) {
+	defer s.lock(id)()
+
+	ss, cur, err := s.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := s.completing.Load(id); ok {
+		return cur, ErrCompleting
+	}
+	if offset != cur {
+		return cur, ErrOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.Path(id), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return cur, fmt.Errorf("cannot open upload file: %w", err)
+	}
+	defer f.Close()
+
+	// read one more byte to detect oversized uploads
+	n, err := io.Copy(f, io.LimitReader(r, ss.Size-cur+1))
+	if err != nil {
+		return cur + n, fmt.Errorf("cannot write upload file: %w", err)
+	}
+	if cur+n > ss.Size {
+		err = f.Truncate(ss.Size)
+		if err != nil {
+			return 0, fmt.Errorf("cannot truncate upload file: %w", err)
+		}
+		return ss.Size, ErrTooLarge
+	}
+	return cur + n, nil
+}
+
+// Complete marks the upload session of the given ID as being completed,
+// so that only one request finalizes the session. Its data must have the
+// declared size. The session is then neither appended to nor collected,
+// and it is finished by Remove.
+func (s *Store) Complete(id string) error {
+	defer s.lock(id)()
+
+	ss, cur, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if cur != ss.Size {
+		return ErrOffsetMismatch
+	}
+	if _, loaded := s.completing.LoadOrStore(id, true); loaded {
+		return ErrCompleting
+	}
+	return nil
+}
+
+// lock locks the upload session of the given ID and returns its unlock.
+func (s *Store) lock(id string) func() {
+	l, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	l.(*sync.Mutex).Lock()
+	return l.(*sync.Mutex).Unlock
+}
+
+// Detect detects the MIME type of the data of the given upload session,
+// see types.DetectMIME.
+func (s *Store) Detect(id string) (types.MIME, error) {
+	f, err := os.Open(s.Path(id))
+	if err != nil {
+		return "", fmt.Errorf("cannot open upload file: %w", err)
+	}
+	defer f.Close()
+
+	// http.DetectContentType considers at most 512 bytes.
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("cannot read upload file: %w", err)
+	}
+	return types.DetectMIME(buf[:n]), nil
+}
+
+// Path returns the path of the data file of the given upload session.
+func (s *Store) Path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// Remove removes the upload session of the given ID.
+func (s *Store) Remove(id string) error {
+	if !valid(id) {
+		return ErrNotFound
+	}
+	s.locks.Delete(id)
+	s.completing.Delete(id)
+	err := os.Remove(s.Path(id) + ".yml")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	err = os.Remove(s.Path(id))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Sessions returns all upload sessions, broken sessions are left out.
+func (s *Store) Sessions() []Session {
+	var sessions []Session
+	for _, id := range s.ids() {
+		if ss, _, err := s.Get(id); err == nil {
+			sessions = append(sessions, ss)
+		}
+	}
+	return sessions
+}
+
+// GC removes all upload sessions that are not appended to since the
+// given time, and broken sessions. Sessions that are being completed
+// are kept. It returns the removed sessions, except the broken ones.
+func (s *Store) GC(before time.Time) []Session {
+	var removed []Session
+	for _, id := range s.ids() {
+		if ss, ok := s.collect(id, before); ok {
+			removed = append(removed, ss)
+		}
+	}
+	return removed
+}
+
+// collect removes the upload session of the given ID if it is stale or
+// broken, and reports whether a stale session is removed.
+func (s *Store) collect(id string, before time.Time) (Session, bool) {
+	defer s.lock(id)()
+	ss, _, err := s.Get(id)
+	if _, ok := s.completing.Load(id); ok || (err == nil && !ss.Updated.Before(before)) {
+		return Session{}, false
+	}
+	return ss, s.Remove(id) == nil && err == nil
+}
+
+// ids returns the IDs of all upload sessions.
+func (s *Store) ids() []string {
+	matches, _ := filepath.Glob(filepath.Join(s.dir, "*.yml"))
+	ids := make([]string, 0, len(matches))
+	for _, m := range matches {
+		id := filepath.Base(m)
+		ids = append(ids, id[:len(id)-len(".yml")])
+	}
+	return ids
+}
+
+// valid reports whether the given ID is a valid session ID, which
+// cannot escape the upload folder.
+func valid(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+// Copyright 2020-2021 Changkun Ou. All rights reserved.
+// Use of this source code is governed by a GPL-3.0
+// license that can be found in the LICENSE file.
+
+package upload_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"changkun.de/x/midgard/internal/types"
+	"changkun.de/x/midgard/internal/upload"
+)
+
+func TestUpload(t *testing.T) {
+	dir := t.TempDir()
+	s := upload.NewStore(dir)
+	ss, err := s.Create("changkun", 10, types.AllocateURLInput{URI: "/a.txt"})
+	if err != nil {
+		t.Fatalf("cannot create upload: %v", err)
+	}
+
+	off, err := s.Append(ss.ID, 0, strings.NewReader("hello"))
+	if err != nil || off != 5 {
+		t.Fatalf("cannot append chunk, offset: %d, err: %v", off, err)
+	}
+	off, err = s.Append(ss.ID, 0, strings.NewReader("hello"))
+	if !errors.Is(err, upload.ErrOffsetMismatch) || off != 5 {
+		t.Fatalf("mismatched offset is accepted, offset: %d, err: %v", off, err)
+	}
+
+	// the upload must survive a restart
+	s = upload.NewStore(dir)
+	got, off, err := s.Get(ss.ID)
+	if err != nil || off != 5 || got.User != "changkun" || got.Alloc.URI != "/a.txt" {
+		t.Fatalf("inconsistent upload, offset: %d, got: %+v, err: %v", off, got, err)
+	}
+
+	off, err = s.Append(ss.ID, 5, strings.NewReader("world!"))
+	if !errors.Is(err, upload.ErrTooLarge) || off != 10 {
+		t.Fatalf("oversized upload is accepted, offset: %d, err: %v", off, err)
+	}
+	b, err := os.ReadFile(s.Path(ss.ID))
+	if err != nil || string(b) != "helloworld" {
+		t.Fatalf("unexpected upload data: %q, err: %v", b, err)
+	}
+
+	if err := s.Remove(ss.ID); err != nil {
+		t.Fatalf("cannot remove upload: %v", err)
+	}
+	if _, _, err := s.Get(ss.ID); !errors.Is(err, upload.ErrNotFound) {
+		t.Fatalf("removed upload still exists, err: %v", err)
+	}
+	if _, _, err := s.Get("../upload"); !errors.Is(err, upload.ErrNotFound) {
+		t.Fatalf("invalid upload id is accepted, err: %v", err)
+	}
+}
+
+func TestUploadGC(t *testing.T) {
+	s := upload.NewStore(t.TempDir())
+	ss, err := s.Create("changkun", 10, types.AllocateURLInput{})
+	if err != nil {
+		t.Fatalf("cannot create upload: %v", err)
+	}
+
+	if removed := s.GC(ss.Created.Add(-time.Minute)); len(removed) != 0 {
+		t.Fatalf("recent upload is collected: %+v", removed)
+	}
+	if _, _, err := s.Get(ss.ID); err != nil {
+		t.Fatalf("recent upload is collected: %v", err)
+	}
+	if sessions := s.Sessions(); len(sessions) != 1 || sessions[0].ID != ss.ID {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	if removed := s.GC(ss.Created.Add(time.Minute)); len(removed) != 1 || removed[0].Size != 10 {
+		t.Fatalf("unexpected collected uploads: %+v", removed)
+	}
+	if _, _, err := s.Get(ss.ID); !errors.Is(err, upload.ErrNotFound) {
+		t.Fatalf("stale upload is not collected, err: %v", err)
+	}
+}
+
+func TestUploadComplete(t *testing.T) {
+	s := upload.NewStore(t.TempDir())
+	ss, err := s.Create("changkun", 5, types.AllocateURLInput{})
+	if err != nil {
+		t.Fatalf("cannot create upload: %v", err)
+	}
+	if err := s.Complete(ss.ID); !errors.Is(err, upload.ErrOffsetMismatch) {
+		t.Fatalf("incomplete upload is completed, err: %v", err)
+	}
+	if _, err := s.Append(ss.ID, 0, strings.NewReader("hello")); err != nil {
+		t.Fatalf("cannot append chunk: %v", err)
+	}
+
+	// e.g. a retry of a timed out request must not complete it again.
+	if err := s.Complete(ss.ID); err != nil {
+		t.Fatalf("cannot complete upload: %v", err)
+	}
+	if err := s.Complete(ss.ID); !errors.Is(err, upload.ErrCompleting) {
+		t.Fatalf("upload is completed twice, err: %v", err)
+	}
+	if _, err := s.Append(ss.ID, 5, strings.NewReader("")); !errors.Is(err, upload.ErrCompleting) {
+		t.Fatalf("completing upload is appended to, err: %v", err)
+	}
+	if removed := s.GC(time.Now().Add(time.Hour)); len(removed) != 0 {
+		t.Fatalf("completing upload is collected: %+v", removed)
+	}
+}
+
+func TestUploadGCActive(t *testing.T) {
+	s := upload.NewStore(t.TempDir())
+	ss, err := s.Create("changkun", 10, types.AllocateURLInput{})
+	if err != nil {
+		t.Fatalf("cannot create upload: %v", err)
+	}
+	if _, err := s.Append(ss.ID, 0, strings.NewReader("hello")); err != nil {
+		t.Fatalf("cannot append chunk: %v", err)
+	}
+	// the chunk is appended an hour after the upload is created.
+	later := ss.Created.Add(time.Hour)
+	if err := os.Chtimes(s.Path(ss.ID), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	// a slow upload is kept as long as it is appended to.
+	if removed := s.GC(ss.Created.Add(time.Minute)); len(removed) != 0 {
+		t.Fatalf("active upload is collected: %+v", removed)
+	}
+	if removed := s.GC(later.Add(time.Minute)); len(removed) != 1 {
+		t.Fatalf("inactive upload is not collected: %+v", removed)
+	}
+}
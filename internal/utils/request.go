@@ -6,6 +6,7 @@ package utils
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -17,6 +18,13 @@ import (
 // Request conducts a http request for a given method, api endpoint, and
 // data attached as application/json Content-Type.
 func Request(method, api string, data interface{}) ([]byte, error) {
+	_, res, err := RequestStatus(method, api, data)
+	return res, err
+}
+
+// RequestStatus is like Request but also returns the status code of the
+// response.
+func RequestStatus(method, api string, data interface{}) (int, []byte, error) {
 	var (
 		body []byte
 		err  error
@@ -24,10 +32,19 @@ func Request(method, api string, data interface{}) ([]byte, error) {
 	if data != nil {
 		body, err = json.Marshal(data)
 		if err != nil {
-			return nil, err
+			return 0, nil, err
 		}
 	}
 
+	return RequestRaw(method, api, http.Header{
+		"Content-Type": []string{"application/json"},
+	}, bytes.NewBuffer(body))
+}
+
+// RequestRaw conducts a http request for a given method, api endpoint,
+// additional headers, and a body that is streamed to the server. It
+// returns the status code and the body of the response.
+func RequestRaw(method, api string, header http.Header, body io.Reader) (int, []byte, error) {
 	if !strings.HasPrefix(api, "https://") || !strings.HasPrefix(api, "http://") {
 		if strings.Contains(config.Get().Domain, "localhost") {
 			api = "http://" + api
@@ -37,16 +54,31 @@ func Request(method, api string, data interface{}) ([]byte, error) {
 	}
 
 	c := &http.Client{}
-	req, err := http.NewRequest(method, api, bytes.NewBuffer(body))
+	req, err := http.NewRequest(method, api, body)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
-	req.SetBasicAuth(config.Get().Server.Auth.User, config.Get().Server.Auth.Pass)
-	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", Authorization())
 	resp, err := c.Do(req)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
-	return io.ReadAll(resp.Body)
+	res, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, res, err
+}
+
+// Authorization returns the value of the Authorization header to access
+// the midgard server. A configured API token is preferred over the user
+// and password.
+func Authorization() string {
+	auth := config.Get().Server.Auth
+	if auth.Token != "" {
+		return "Bearer " + auth.Token
+	}
+	creds := auth.User + ":" + auth.Pass
+	return "Basic " + base64.StdEncoding.EncodeToString(StringToBytes(creds))
 }